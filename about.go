@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AboutContent represents the content of the about page in one locale.
+type AboutContent struct {
+	Name     string       `json:"name"`     // Name is the name shown as the page heading.
+	Headline string       `json:"headline"` // Headline is the short line shown under the name.
+	Bio      []string     `json:"bio"`      // Bio holds the paragraphs of the biography in Markdown.
+	Skills   []string     `json:"skills"`   // Skills is the list of professional skills.
+	CVUrl    string       `json:"cv_url"`   // CVUrl is the URL of the curriculum vitae.
+	Portrait string       `json:"portrait"` // Portrait is the URL of the portrait image.
+	Socials  []SocialLink `json:"socials"`  // Socials are the social profiles to link to.
+}
+
+// SocialLink represents a link to a social profile.
+type SocialLink struct {
+	Name string `json:"name"`           // Name is the name of the network, e.g. "GitHub".
+	URL  string `json:"url"`            // URL is the URL of the profile.
+	Icon string `json:"icon,omitempty"` // Icon is the icon shown in the footer: github, mastodon, youtube or twitter.
+}
+
+// AboutPage represents the about page.
+type AboutPage struct {
+	Layout
+	About AboutContent    // About is the content of the page.
+	Bio   []template.HTML // Bio holds the rendered paragraphs of the biography.
+}
+
+// AboutDocument holds the about page content for every locale, keyed by locale.
+type AboutDocument map[string]AboutContent
+
+// LoadAbout loads the about page content from the JSON file at path,
+// or from content/about.json within fsys if path is empty.
+// The document is validated for the given locales; see AboutDocument.Validate.
+func LoadAbout(fsys fs.FS, path string, locales []string) (AboutDocument, error) {
+	var data []byte
+	var err error
+	if path != "" {
+		data, err = os.ReadFile(path)
+	} else {
+		data, err = fs.ReadFile(fsys, "content/about.json")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading about content: %w", err)
+	}
+	return parseAbout(data, locales)
+}
+
+// fetchAboutFromAPI fetches the about page content from the API at aboutUrl,
+// which returns the same document as the content file.
+func fetchAboutFromAPI(aboutUrl string, locales []string) (AboutDocument, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	req, err := http.NewRequest("GET", aboutUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; GoClient/1.1)")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received non-200 status code %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	return parseAbout(data, locales)
+}
+
+// parseAbout decodes and validates an about document.
+func parseAbout(data []byte, locales []string) (AboutDocument, error) {
+	var doc AboutDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error decoding about content: %w", err)
+	}
+	if err := doc.Validate(locales); err != nil {
+		return nil, fmt.Errorf("invalid about content: %w", err)
+	}
+	return doc, nil
+}
+
+// Validate checks that the document has complete content for the default locale, the first of locales,
+// and that every URL in it is valid. Other locales may leave fields empty to use the default's.
+func (doc AboutDocument) Validate(locales []string) error {
+	var errs []error
+
+	if len(locales) == 0 {
+		return errors.New("no locales configured")
+	}
+	defaults, ok := doc[locales[0]]
+	if !ok {
+		return fmt.Errorf("missing content for the default locale %s", locales[0])
+	}
+	if defaults.Name == "" {
+		errs = append(errs, fmt.Errorf("%s: name is required", locales[0]))
+	}
+	if len(defaults.Bio) == 0 {
+		errs = append(errs, fmt.Errorf("%s: bio must have at least one paragraph", locales[0]))
+	}
+	if defaults.Portrait == "" {
+		errs = append(errs, fmt.Errorf("%s: portrait is required", locales[0]))
+	}
+
+	for locale, content := range doc {
+		if !slices.Contains(locales, locale) {
+			errs = append(errs, fmt.Errorf("%s: locale is not in LOCALES", locale))
+		}
+		for i, paragraph := range content.Bio {
+			if strings.TrimSpace(paragraph) == "" {
+				errs = append(errs, fmt.Errorf("%s: bio paragraph %d is empty", locale, i+1))
+			}
+		}
+		if content.CVUrl != "" {
+			if err := validateURL(content.CVUrl); err != nil {
+				errs = append(errs, fmt.Errorf("%s: cv_url %w", locale, err))
+			}
+		}
+		if content.Portrait != "" && !strings.HasPrefix(content.Portrait, "/static/") {
+			if err := validateURL(content.Portrait); err != nil {
+				errs = append(errs, fmt.Errorf("%s: portrait must be a /static/ path or %w", locale, err))
+			}
+		}
+		for _, social := range content.Socials {
+			if social.Name == "" {
+				errs = append(errs, fmt.Errorf("%s: social link %q has no name", locale, social.URL))
+			}
+			if err := validateURL(social.URL); err != nil {
+				errs = append(errs, fmt.Errorf("%s: social link %q %w", locale, social.Name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// For returns the content for locale, with empty fields filled in from the default locale.
+func (doc AboutDocument) For(locale, defaultLocale string) AboutContent {
+	content, defaults := doc[locale], doc[defaultLocale]
+	content.Name = orDefault(content.Name, defaults.Name)
+	content.Headline = orDefault(content.Headline, defaults.Headline)
+	content.CVUrl = orDefault(content.CVUrl, defaults.CVUrl)
+	content.Portrait = orDefault(content.Portrait, defaults.Portrait)
+	if len(content.Bio) == 0 {
+		content.Bio = defaults.Bio
+	}
+	if len(content.Skills) == 0 {
+		content.Skills = defaults.Skills
+	}
+	if len(content.Socials) == 0 {
+		content.Socials = defaults.Socials
+	}
+	return content
+}
+
+// LoadAboutContent loads the about page content into the App.
+// It is fetched from ABOUT_API when set, falling back to the file at ABOUT_CONFIG or content/about.json
+// if the API is unreachable or returns invalid content. An invalid content file is an error.
+func (a *App) LoadAboutContent() error {
+	if a.Config.AboutAPI != "" {
+		doc, err := fetchAboutFromAPI(a.Config.AboutAPI, a.Config.Locales)
+		if err == nil {
+			a.AboutContent = doc
+			return nil
+		}
+		a.logger.Printf("Error fetching about content, using the content file: %s\n", err)
+	}
+
+	doc, err := LoadAbout(a.Assets, a.Config.AboutConfig, a.Config.Locales)
+	if err != nil {
+		return err
+	}
+	a.AboutContent = doc
+	return nil
+}
+
+// AboutHandler handles the HTTP request for the about page.
+// It renders the about content in the page's locale, with the biography converted from Markdown.
+func (a *App) AboutHandler(w http.ResponseWriter, r *http.Request) {
+	layout := a.layout(r, a.T(r, "title.about"))
+	content := a.AboutContent.For(layout.Locale, a.Translations.Default())
+	if len(content.Socials) == 0 {
+		content.Socials = layout.Site.Socials
+	}
+
+	person := a.personSchema(r, layout)
+	person.Name, person.Image, person.Description = content.Name, a.absoluteImage(r, content.Portrait), content.Headline
+	person.KnowsAbout = content.Skills
+	layout.SEO.Description = orDefault(content.Headline, layout.SEO.Description)
+	layout.SEO.Image = orDefault(person.Image, layout.SEO.Image)
+	layout.SEO.Type = "profile"
+	layout.SEO.StructuredData = []any{person}
+
+	page := AboutPage{Layout: layout, About: content}
+	for _, paragraph := range content.Bio {
+		page.Bio = append(page.Bio, renderMarkdown(paragraph))
+	}
+
+	a.Render(w, r, http.StatusOK, "templates/about.html", page)
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// acmeTimeout is how long the ACME client waits for an authorization or order to become valid.
+const acmeTimeout = 2 * time.Minute
+
+// ACMEClient is a minimal RFC 8555 client that obtains certificates from an ACME CA such as
+// Let's Encrypt, or Pebble for testing, using the http-01 challenge. It covers what this server
+// needs: registering an account, ordering a certificate for a list of host names,
+// answering the challenges and downloading the certificate chain.
+type ACMEClient struct {
+	directoryURL string
+	email        string
+	key          *ecdsa.PrivateKey // key is the account key every request is signed with.
+	client       *http.Client
+
+	mu        sync.Mutex
+	directory acmeDirectory
+	account   string   // account is the account URL, sent as the key ID once registered.
+	nonces    []string // nonces are unused anti-replay nonces returned by the CA.
+}
+
+// acmeDirectory lists the endpoints of an ACME CA.
+type acmeDirectory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+}
+
+// acmeOrder is a certificate order.
+type acmeOrder struct {
+	Status         string   `json:"status"`
+	Authorizations []string `json:"authorizations"`
+	Finalize       string   `json:"finalize"`
+	Certificate    string   `json:"certificate"`
+}
+
+// acmeAuthorization is the CA's authorization of the account to get certificates for one host name.
+type acmeAuthorization struct {
+	Status     string `json:"status"`
+	Identifier struct {
+		Value string `json:"value"`
+	} `json:"identifier"`
+	Challenges []acmeChallenge `json:"challenges"`
+}
+
+// acmeChallenge is a way of proving control of a host name.
+type acmeChallenge struct {
+	Type   string       `json:"type"`
+	URL    string       `json:"url"`
+	Token  string       `json:"token"`
+	Status string       `json:"status"`
+	Error  *acmeProblem `json:"error"`
+}
+
+// acmeProblem is an error returned by the CA, as RFC 7807 problem details.
+type acmeProblem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+}
+
+// Error returns the problem type and detail.
+func (p *acmeProblem) Error() string {
+	return fmt.Sprintf("%s: %s", p.Type, p.Detail)
+}
+
+// NewACMEClient creates an ACMEClient for the CA at directoryURL, signing requests with the account key.
+func NewACMEClient(directoryURL, email string, key *ecdsa.PrivateKey, client *http.Client) *ACMEClient {
+	return &ACMEClient{directoryURL: directoryURL, email: email, key: key, client: client}
+}
+
+// Obtain orders a certificate for hosts and returns its PEM certificate chain.
+// solve is called with the token and key authorization of every http-01 challenge before the
+// CA is asked to validate it, and must serve the key authorization at
+// /.well-known/acme-challenge/{token} over HTTP on port 80 of the host until Obtain returns.
+func (c *ACMEClient) Obtain(hosts []string, certKey crypto.Signer, solve func(token, keyAuth string)) ([]byte, error) {
+	if err := c.register(); err != nil {
+		return nil, fmt.Errorf("error registering account: %w", err)
+	}
+
+	identifiers := make([]map[string]string, 0, len(hosts))
+	for _, host := range hosts {
+		identifiers = append(identifiers, map[string]string{"type": "dns", "value": host})
+	}
+	var order acmeOrder
+	resp, err := c.post(c.directory.NewOrder, map[string]any{"identifiers": identifiers}, &order)
+	if err != nil {
+		return nil, fmt.Errorf("error creating order: %w", err)
+	}
+	orderURL := resp.Header.Get("Location")
+
+	for _, authzURL := range order.Authorizations {
+		if err := c.authorize(authzURL, solve); err != nil {
+			return nil, err
+		}
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: hosts[0]},
+		DNSNames: hosts,
+	}, certKey)
+	if err != nil {
+		return nil, fmt.Errorf("error creating certificate request: %w", err)
+	}
+	if _, err := c.post(order.Finalize, map[string]string{"csr": base64.RawURLEncoding.EncodeToString(csr)}, &order); err != nil {
+		return nil, fmt.Errorf("error finalizing order: %w", err)
+	}
+
+	deadline := time.Now().Add(acmeTimeout)
+	for order.Status != "valid" {
+		if order.Status == "invalid" || time.Now().After(deadline) {
+			return nil, fmt.Errorf("order is %s", order.Status)
+		}
+		time.Sleep(time.Second)
+		if _, err := c.post(orderURL, nil, &order); err != nil {
+			return nil, fmt.Errorf("error polling order: %w", err)
+		}
+	}
+
+	resp, err = c.post(order.Certificate, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading certificate: %w", err)
+	}
+	defer resp.Body.Close()
+	chain, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading certificate: %w", err)
+	}
+	return chain, nil
+}
+
+// register fetches the CA's directory and creates the account, or looks up the existing account of the key.
+func (c *ACMEClient) register() error {
+	c.mu.Lock()
+	registered := c.account != ""
+	c.mu.Unlock()
+	if registered {
+		return nil
+	}
+
+	resp, err := c.client.Get(c.directoryURL)
+	if err != nil {
+		return fmt.Errorf("error fetching directory: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received non-200 status code %d fetching directory", resp.StatusCode)
+	}
+	var directory acmeDirectory
+	if err := json.NewDecoder(resp.Body).Decode(&directory); err != nil {
+		return fmt.Errorf("error decoding directory: %w", err)
+	}
+	c.mu.Lock()
+	c.directory = directory
+	c.mu.Unlock()
+
+	account := map[string]any{"termsOfServiceAgreed": true}
+	if c.email != "" {
+		account["contact"] = []string{"mailto:" + c.email}
+	}
+	resp, err = c.post(directory.NewAccount, account, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	c.mu.Lock()
+	c.account = resp.Header.Get("Location")
+	c.mu.Unlock()
+	return nil
+}
+
+// authorize proves control of the host name of the authorization at authzURL with its http-01 challenge.
+func (c *ACMEClient) authorize(authzURL string, solve func(token, keyAuth string)) error {
+	var authz acmeAuthorization
+	if _, err := c.post(authzURL, nil, &authz); err != nil {
+		return fmt.Errorf("error fetching authorization: %w", err)
+	}
+	if authz.Status == "valid" {
+		return nil
+	}
+
+	var challenge *acmeChallenge
+	for i := range authz.Challenges {
+		if authz.Challenges[i].Type == "http-01" {
+			challenge = &authz.Challenges[i]
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("no http-01 challenge offered for %s", authz.Identifier.Value)
+	}
+
+	solve(challenge.Token, challenge.Token+"."+jwkThumbprint(&c.key.PublicKey))
+	if _, err := c.post(challenge.URL, struct{}{}, challenge); err != nil {
+		return fmt.Errorf("error accepting challenge for %s: %w", authz.Identifier.Value, err)
+	}
+
+	deadline := time.Now().Add(acmeTimeout)
+	for authz.Status != "valid" {
+		if authz.Status == "invalid" || time.Now().After(deadline) {
+			for _, ch := range authz.Challenges {
+				if ch.Error != nil {
+					return fmt.Errorf("authorization for %s is %s: %w", authz.Identifier.Value, authz.Status, ch.Error)
+				}
+			}
+			return fmt.Errorf("authorization for %s is %s", authz.Identifier.Value, authz.Status)
+		}
+		time.Sleep(time.Second)
+		if _, err := c.post(authzURL, nil, &authz); err != nil {
+			return fmt.Errorf("error polling authorization: %w", err)
+		}
+	}
+	return nil
+}
+
+// post sends payload to url as a JWS signed with the account key and decodes the JSON response into v,
+// if v is not nil. A nil payload sends a POST-as-GET request. A request rejected for a bad nonce
+// is retried once with a fresh nonce. If v is nil the caller must close the response body.
+func (c *ACMEClient) post(url string, payload any, v any) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		body, err := c.sign(url, payload)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.client.Post(url, "application/jose+json", bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("error making request: %w", err)
+		}
+		if nonce := resp.Header.Get("Replay-Nonce"); nonce != "" {
+			c.mu.Lock()
+			c.nonces = append(c.nonces, nonce)
+			c.mu.Unlock()
+		}
+
+		if resp.StatusCode >= 400 {
+			problem := &acmeProblem{}
+			json.NewDecoder(resp.Body).Decode(problem)
+			resp.Body.Close()
+			if problem.Type == "urn:ietf:params:acme:error:badNonce" && attempt == 0 {
+				continue
+			}
+			if problem.Type == "" {
+				return nil, fmt.Errorf("received status code %d", resp.StatusCode)
+			}
+			return nil, problem
+		}
+
+		if v == nil {
+			return resp, nil
+		}
+		defer resp.Body.Close()
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return nil, fmt.Errorf("error decoding response: %w", err)
+		}
+		return resp, nil
+	}
+}
+
+// sign returns the flattened JWS of payload for url. Requests are identified by the account URL
+// once the account is registered, and by the public key before.
+func (c *ACMEClient) sign(url string, payload any) ([]byte, error) {
+	nonce, err := c.nonce()
+	if err != nil {
+		return nil, fmt.Errorf("error getting nonce: %w", err)
+	}
+
+	c.mu.Lock()
+	protected := map[string]any{"alg": "ES256", "nonce": nonce, "url": url}
+	if c.account != "" {
+		protected["kid"] = c.account
+	} else {
+		protected["jwk"] = jwk(&c.key.PublicKey)
+	}
+	c.mu.Unlock()
+
+	header, err := json.Marshal(protected)
+	if err != nil {
+		return nil, err
+	}
+	encodedPayload := ""
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		encodedPayload = base64.RawURLEncoding.EncodeToString(data)
+	}
+	encodedHeader := base64.RawURLEncoding.EncodeToString(header)
+
+	digest := sha256.Sum256([]byte(encodedHeader + "." + encodedPayload))
+	r, s, err := ecdsa.Sign(rand.Reader, c.key, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("error signing request: %w", err)
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return json.Marshal(map[string]string{
+		"protected": encodedHeader,
+		"payload":   encodedPayload,
+		"signature": base64.RawURLEncoding.EncodeToString(signature),
+	})
+}
+
+// nonce returns an unused nonce, fetching a new one from the CA if there is none left.
+func (c *ACMEClient) nonce() (string, error) {
+	c.mu.Lock()
+	if n := len(c.nonces); n > 0 {
+		nonce := c.nonces[n-1]
+		c.nonces = c.nonces[:n-1]
+		c.mu.Unlock()
+		return nonce, nil
+	}
+	newNonce := c.directory.NewNonce
+	c.mu.Unlock()
+
+	resp, err := c.client.Head(newNonce)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	nonce := resp.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return "", errors.New("no Replay-Nonce header in response")
+	}
+	return nonce, nil
+}
+
+// jwk returns the JSON Web Key of a P-256 public key.
+// The members are in lexicographic order, as the thumbprint requires.
+func jwk(key *ecdsa.PublicKey) map[string]string {
+	x, y := make([]byte, 32), make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return map[string]string{
+		"crv": "P-256",
+		"kty": "EC",
+		"x":   base64.RawURLEncoding.EncodeToString(x),
+		"y":   base64.RawURLEncoding.EncodeToString(y),
+	}
+}
+
+// jwkThumbprint returns the RFC 7638 thumbprint of key, which is part of every key authorization.
+// encoding/json writes map keys in sorted order, which is the canonical form the thumbprint is taken of.
+func jwkThumbprint(key *ecdsa.PublicKey) string {
+	data, _ := json.Marshal(jwk(key))
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// loadOrCreateKey reads a PEM encoded P-256 private key from name, or generates and saves one
+// if the file does not exist.
+func loadOrCreateKey(name string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(name)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s is not a PEM file", name)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating key: %w", err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := saveFile(name, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// decodeB64 decodes unpadded base64url, failing the test on malformed input.
+func decodeB64(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decoding %q: %s", s, err)
+	}
+	return data
+}
+
+func TestJWKThumbprint(t *testing.T) {
+	// The P-256 public key of RFC 7517 appendix A.1. Its thumbprint is the base64url SHA-256 of the
+	// RFC 7638 canonical form {"crv":"P-256","kty":"EC","x":"...","y":"..."}.
+	x := "MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4"
+	y := "4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM"
+	key := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(decodeB64(t, x)),
+		Y:     new(big.Int).SetBytes(decodeB64(t, y)),
+	}
+
+	canonical, err := json.Marshal(jwk(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"crv":"P-256","kty":"EC","x":"` + x + `","y":"` + y + `"}`; string(canonical) != want {
+		t.Errorf("jwk = %s, want %s", canonical, want)
+	}
+	if got, want := jwkThumbprint(key), "cn-I_WNMClehiVp51i_0VpOENW1upEerA8sEam5hn-s"; got != want {
+		t.Errorf("jwkThumbprint = %s, want %s", got, want)
+	}
+}
+
+// jwsRequest is a decoded flattened JWS as sent by ACMEClient.
+type jwsRequest struct {
+	Protected struct {
+		Alg   string            `json:"alg"`
+		Nonce string            `json:"nonce"`
+		URL   string            `json:"url"`
+		KID   string            `json:"kid"`
+		JWK   map[string]string `json:"jwk"`
+	}
+	Payload []byte
+}
+
+// verifyJWS decodes a flattened JWS and verifies its ES256 signature with key,
+// or with the key embedded in the protected header if key is nil.
+func verifyJWS(body []byte, key *ecdsa.PublicKey) (jwsRequest, *ecdsa.PublicKey, error) {
+	var req jwsRequest
+	var jws struct {
+		Protected string `json:"protected"`
+		Payload   string `json:"payload"`
+		Signature string `json:"signature"`
+	}
+	if err := json.Unmarshal(body, &jws); err != nil {
+		return req, nil, err
+	}
+	header, err := base64.RawURLEncoding.DecodeString(jws.Protected)
+	if err != nil {
+		return req, nil, err
+	}
+	if err := json.Unmarshal(header, &req.Protected); err != nil {
+		return req, nil, err
+	}
+	if req.Payload, err = base64.RawURLEncoding.DecodeString(jws.Payload); err != nil {
+		return req, nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(jws.Signature)
+	if err != nil || len(signature) != 64 {
+		return req, nil, fmt.Errorf("malformed signature")
+	}
+
+	if key == nil {
+		if req.Protected.JWK == nil {
+			return req, nil, fmt.Errorf("no jwk in protected header")
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(req.Protected.JWK["x"])
+		y, errY := base64.RawURLEncoding.DecodeString(req.Protected.JWK["y"])
+		if errX != nil || errY != nil {
+			return req, nil, fmt.Errorf("malformed jwk")
+		}
+		key = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	}
+
+	digest := sha256.Sum256([]byte(jws.Protected + "." + jws.Payload))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(key, digest[:], r, s) {
+		return req, nil, fmt.Errorf("invalid signature")
+	}
+	return req, key, nil
+}
+
+func TestSign(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewACMEClient("https://ca.test/dir", "", key, http.DefaultClient)
+	c.nonces = []string{"nonce-1", "nonce-2"}
+
+	// Before registration requests carry the public key.
+	body, err := c.sign("https://ca.test/new-account", map[string]bool{"termsOfServiceAgreed": true})
+	if err != nil {
+		t.Fatalf("sign: %s", err)
+	}
+	req, _, err := verifyJWS(body, &key.PublicKey)
+	if err != nil {
+		t.Fatalf("verifying JWS: %s", err)
+	}
+	if req.Protected.Alg != "ES256" || req.Protected.Nonce != "nonce-2" || req.Protected.URL != "https://ca.test/new-account" {
+		t.Errorf("protected header = %+v", req.Protected)
+	}
+	if req.Protected.KID != "" || req.Protected.JWK["x"] != jwk(&key.PublicKey)["x"] {
+		t.Errorf("protected header identifies the account by %q and %v, want the jwk", req.Protected.KID, req.Protected.JWK)
+	}
+	if string(req.Payload) != `{"termsOfServiceAgreed":true}` {
+		t.Errorf("payload = %s", req.Payload)
+	}
+
+	// Once registered requests carry the account URL, and POST-as-GET requests have an empty payload.
+	c.account = "https://ca.test/account/1"
+	body, err = c.sign("https://ca.test/order/1", nil)
+	if err != nil {
+		t.Fatalf("sign: %s", err)
+	}
+	req, _, err = verifyJWS(body, &key.PublicKey)
+	if err != nil {
+		t.Fatalf("verifying JWS: %s", err)
+	}
+	if req.Protected.KID != "https://ca.test/account/1" || req.Protected.JWK != nil || req.Protected.Nonce != "nonce-1" {
+		t.Errorf("protected header = %+v", req.Protected)
+	}
+	if len(req.Payload) != 0 {
+		t.Errorf("payload = %q, want empty", req.Payload)
+	}
+}
+
+// fakeCA is a minimal ACME CA for testing: it verifies every JWS and nonce, rejects the first
+// new order with badNonce, validates http-01 challenges by checking the key authorization
+// the client handed to its solver, and issues certificates signed by its own key.
+type fakeCA struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *ecdsa.PrivateKey
+	root   *x509.Certificate
+
+	mu             sync.Mutex
+	nonce          int
+	nonces         map[string]bool
+	accountKey     *ecdsa.PublicKey
+	solved         map[string]string // solved holds the key authorizations the client is serving, by token.
+	badNonceSent   bool
+	authzStatus    string
+	order          acmeOrder
+	hosts          []string
+	certificateDER []byte
+}
+
+func newFakeCA(t *testing.T) *fakeCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Fake CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ := x509.ParseCertificate(der)
+
+	ca := &fakeCA{t: t, key: key, root: root, nonces: make(map[string]bool), solved: make(map[string]string), authzStatus: "pending"}
+	ca.server = httptest.NewServer(http.HandlerFunc(ca.serve))
+	t.Cleanup(ca.server.Close)
+	return ca
+}
+
+func (ca *fakeCA) url(path string) string {
+	return ca.server.URL + path
+}
+
+// problem writes an ACME problem document.
+func (ca *fakeCA) problem(w http.ResponseWriter, status int, typ, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(acmeProblem{Type: "urn:ietf:params:acme:error:" + typ, Detail: detail})
+}
+
+func (ca *fakeCA) serve(w http.ResponseWriter, r *http.Request) {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	if r.URL.Path == "/dir" {
+		json.NewEncoder(w).Encode(acmeDirectory{NewNonce: ca.url("/new-nonce"), NewAccount: ca.url("/new-account"), NewOrder: ca.url("/new-order")})
+		return
+	}
+
+	ca.nonce++
+	nonce := fmt.Sprintf("nonce-%d", ca.nonce)
+	ca.nonces[nonce] = true
+	w.Header().Set("Replay-Nonce", nonce)
+	if r.URL.Path == "/new-nonce" {
+		return
+	}
+
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/jose+json" {
+		ca.problem(w, http.StatusMethodNotAllowed, "malformed", "requests must be JWS POSTs")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		ca.problem(w, http.StatusBadRequest, "malformed", err.Error())
+		return
+	}
+
+	key := ca.accountKey
+	if r.URL.Path == "/new-account" {
+		key = nil
+	}
+	req, key, err := verifyJWS(body, key)
+	if err != nil {
+		ca.problem(w, http.StatusBadRequest, "malformed", err.Error())
+		return
+	}
+	if req.Protected.URL != ca.url(r.URL.Path) {
+		ca.problem(w, http.StatusBadRequest, "unauthorized", "url does not match")
+		return
+	}
+	if !ca.nonces[req.Protected.Nonce] {
+		ca.problem(w, http.StatusBadRequest, "badNonce", "unknown nonce")
+		return
+	}
+	delete(ca.nonces, req.Protected.Nonce)
+	if r.URL.Path != "/new-account" && req.Protected.KID != ca.url("/account/1") {
+		ca.problem(w, http.StatusUnauthorized, "unauthorized", "unknown account")
+		return
+	}
+
+	switch r.URL.Path {
+	case "/new-account":
+		ca.accountKey = key
+		w.Header().Set("Location", ca.url("/account/1"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"status":"valid"}`))
+
+	case "/new-order":
+		if !ca.badNonceSent {
+			ca.badNonceSent = true
+			ca.problem(w, http.StatusBadRequest, "badNonce", "try again")
+			return
+		}
+		var payload struct {
+			Identifiers []struct{ Value string } `json:"identifiers"`
+		}
+		json.Unmarshal(req.Payload, &payload)
+		for _, id := range payload.Identifiers {
+			ca.hosts = append(ca.hosts, id.Value)
+		}
+		ca.order = acmeOrder{Status: "pending", Authorizations: []string{ca.url("/authz/1")}, Finalize: ca.url("/finalize/1")}
+		w.Header().Set("Location", ca.url("/order/1"))
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(ca.order)
+
+	case "/authz/1":
+		json.NewEncoder(w).Encode(ca.authorization())
+
+	case "/challenge/1":
+		// A real CA fetches the key authorization over HTTP; the fake one checks what the client serves.
+		if want := "token-1." + jwkThumbprint(ca.accountKey); ca.solved["token-1"] != want {
+			ca.problem(w, http.StatusForbidden, "unauthorized", fmt.Sprintf("key authorization is %q, want %q", ca.solved["token-1"], want))
+			return
+		}
+		ca.authzStatus = "valid"
+		json.NewEncoder(w).Encode(ca.authorization().Challenges[0])
+
+	case "/finalize/1":
+		var payload struct {
+			CSR string `json:"csr"`
+		}
+		json.Unmarshal(req.Payload, &payload)
+		csr, err := x509.ParseCertificateRequest(decodeB64(ca.t, payload.CSR))
+		if err != nil || csr.CheckSignature() != nil || !slices.Equal(csr.DNSNames, ca.hosts) {
+			ca.problem(w, http.StatusBadRequest, "badCSR", "invalid CSR")
+			return
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      pkix.Name{CommonName: ca.hosts[0]},
+			DNSNames:     ca.hosts,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+		ca.certificateDER, err = x509.CreateCertificate(rand.Reader, template, ca.root, csr.PublicKey, ca.key)
+		if err != nil {
+			ca.t.Errorf("issuing certificate: %s", err)
+		}
+		// The order is still being processed when finalize returns, so the client has to poll it.
+		ca.order.Status = "processing"
+		json.NewEncoder(w).Encode(ca.order)
+		ca.order.Status, ca.order.Certificate = "valid", ca.url("/certificate/1")
+
+	case "/order/1":
+		json.NewEncoder(w).Encode(ca.order)
+
+	case "/certificate/1":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: ca.certificateDER})
+		pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: ca.root.Raw})
+
+	default:
+		ca.problem(w, http.StatusNotFound, "malformed", "not found")
+	}
+}
+
+// authorization returns the authorization of the order's host.
+func (ca *fakeCA) authorization() acmeAuthorization {
+	authz := acmeAuthorization{Status: ca.authzStatus}
+	authz.Identifier.Value = ca.hosts[0]
+	authz.Challenges = []acmeChallenge{
+		{Type: "dns-01", URL: ca.url("/challenge/2"), Token: "token-2", Status: "pending"},
+		{Type: "http-01", URL: ca.url("/challenge/1"), Token: "token-1", Status: ca.authzStatus},
+	}
+	return authz
+}
+
+func TestObtain(t *testing.T) {
+	ca := newFakeCA(t)
+	accountKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewACMEClient(ca.url("/dir"), "admin@portfolio.test", accountKey, ca.server.Client())
+	chain, err := client.Obtain([]string{"portfolio.test"}, certKey, func(token, keyAuth string) {
+		ca.mu.Lock()
+		defer ca.mu.Unlock()
+		ca.solved[token] = keyAuth
+	})
+	if err != nil {
+		t.Fatalf("Obtain: %s", err)
+	}
+
+	block, rest := pem.Decode(chain)
+	if block == nil || !strings.Contains(string(rest), "CERTIFICATE") {
+		t.Fatalf("chain is not a PEM chain of two certificates:\n%s", chain)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("parsing certificate: %s", err)
+	}
+	if err := cert.VerifyHostname("portfolio.test"); err != nil {
+		t.Errorf("certificate: %s", err)
+	}
+	if !certKey.PublicKey.Equal(cert.PublicKey) {
+		t.Error("certificate is not for the certificate key")
+	}
+	if err := cert.CheckSignatureFrom(ca.root); err != nil {
+		t.Errorf("certificate is not signed by the CA: %s", err)
+	}
+	if !ca.badNonceSent {
+		t.Error("the badNonce rejection was not retried")
+	}
+	if _, ok := ca.solved["token-2"]; ok {
+		t.Error("the dns-01 challenge was solved")
+	}
+}
+
+func TestObtainInvalidKeyAuthorization(t *testing.T) {
+	ca := newFakeCA(t)
+	accountKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	certKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	// A solver that does not serve the challenge fails validation.
+	client := NewACMEClient(ca.url("/dir"), "", accountKey, ca.server.Client())
+	_, err := client.Obtain([]string{"portfolio.test"}, certKey, func(token, keyAuth string) {})
+	if err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Fatalf("Obtain error = %v, want an unauthorized problem", err)
+	}
+}
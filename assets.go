@@ -0,0 +1,175 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AssetManifest maps the files in the static directory to fingerprinted names that contain a hash
+// of their content, e.g. css/style.css to css/style.3f2a1b9c.css. Pages link to the fingerprinted
+// names, which change whenever a file does, so they can be cached forever and a deploy still
+// serves fresh CSS and JavaScript.
+// The files are hashed at startup. In development the static directory is watched and the
+// files are hashed again whenever one changes.
+type AssetManifest struct {
+	logger *log.Logger
+	fsys   fs.FS // fsys holds the static files.
+
+	mu          sync.RWMutex
+	files       map[string]string // files maps file names to fingerprinted names.
+	hashes      map[string]string // hashes maps file names to the hashes of their content.
+	fingerprint map[string]string // fingerprint maps fingerprinted names back to file names.
+	signature   string
+}
+
+// NewAssetManifest creates an empty AssetManifest for the static files in fsys.
+func NewAssetManifest(logger *log.Logger, fsys fs.FS) *AssetManifest {
+	return &AssetManifest{logger: logger, fsys: fsys}
+}
+
+// Load hashes every file in the static directory and replaces the manifest.
+func (m *AssetManifest) Load() error {
+	files := make(map[string]string)
+	hashes := make(map[string]string)
+	fingerprint := make(map[string]string)
+	signature := dirSignature(m.fsys, ".")
+
+	err := fs.WalkDir(m.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		// Precompressed siblings are served in place of the file they compress, not linked to.
+		ext := path.Ext(name)
+		if ext == ".gz" {
+			return nil
+		}
+		hash, err := hashFile(m.fsys, name)
+		if err != nil {
+			return fmt.Errorf("error hashing %s: %w", name, err)
+		}
+		hashed := strings.TrimSuffix(name, ext) + "." + hash + ext
+		files[name], hashes[name], fingerprint[hashed] = hashed, hash, name
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.files, m.hashes, m.fingerprint, m.signature = files, hashes, fingerprint, signature
+	m.mu.Unlock()
+	return nil
+}
+
+// hashFile returns the first 8 hex digits of the SHA-256 hash of the file's content.
+func hashFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)[:4]), nil
+}
+
+// URL returns the URL of a static file under its fingerprinted name,
+// or under its own name if it is not in the manifest.
+func (m *AssetManifest) URL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if hashed, ok := m.files[name]; ok {
+		return "/static/" + hashed
+	}
+	return "/static/" + name
+}
+
+// Resolve returns the name of the file a static path refers to, whether the path is fingerprinted,
+// and the hash of the file's content, which is empty for files not in the manifest.
+func (m *AssetManifest) Resolve(p string) (string, bool, string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if name, ok := m.fingerprint[p]; ok {
+		return name, true, m.hashes[name]
+	}
+	return p, false, m.hashes[p]
+}
+
+// Files returns a copy of the manifest, mapping file names to fingerprinted names.
+func (m *AssetManifest) Files() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	files := make(map[string]string, len(m.files))
+	for name, hashed := range m.files {
+		files[name] = hashed
+	}
+	return files
+}
+
+// Watch polls the static directory every interval and hashes the files again
+// when one is added, removed or modified. It returns when stop is closed.
+func (m *AssetManifest) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.mu.RLock()
+			changed := dirSignature(m.fsys, ".") != m.signature
+			m.mu.RUnlock()
+			if !changed {
+				continue
+			}
+
+			if err := m.Load(); err != nil {
+				m.logger.Printf("Error reloading static asset manifest: %s\n", err)
+				continue
+			}
+			m.logger.Println("Reloaded static asset manifest")
+		}
+	}
+}
+
+// CacheStaticAssets hashes the static files into the App's asset manifest.
+// In development with static files served from disk the static directory is watched for changes.
+func (a *App) CacheStaticAssets(static fs.FS) {
+	a.StaticAssets = NewAssetManifest(a.logger, static)
+	if err := a.StaticAssets.Load(); err != nil {
+		a.logger.Fatalf("Error hashing static assets: %s\n", err)
+	}
+
+	if !a.Config.IsProduction() && a.Config.AssetsDir != "" {
+		a.logger.Println("Watching static assets for changes")
+		go a.StaticAssets.Watch(500*time.Millisecond, nil)
+	}
+}
+
+// AssetManifestHandler handles the HTTP request for /static-manifest.json, which lists every
+// static file with its fingerprinted name, to check which version of a file pages link to.
+func (a *App) AssetManifestHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := json.MarshalIndent(a.StaticAssets.Files(), "", "  ")
+	if err != nil {
+		a.RenderError(w, r, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(data)
+}
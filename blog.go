@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PostBody represents a full blog post as returned by the blog API.
+type PostBody struct {
+	Post
+	Body   string `json:"body"`   // Body is the content of the post.
+	Format string `json:"format"` // Format is either "markdown" or "html".
+}
+
+// HTML returns the body of the post rendered as HTML.
+// Markdown is converted and escaped; HTML comes from our own blog API and is trusted as is.
+func (p PostBody) HTML() template.HTML {
+	if strings.EqualFold(p.Format, "html") {
+		return template.HTML(p.Body)
+	}
+	return renderMarkdown(p.Body)
+}
+
+// BlogPage represents the blog index page.
+type BlogPage struct {
+	Layout
+	Posts []Post // The posts to list.
+}
+
+// PostPage represents a single blog post page.
+type PostPage struct {
+	Layout
+	Post Post          // The post being displayed.
+	Body template.HTML // The rendered body of the post.
+}
+
+// PostCache caches full post bodies fetched from the blog API.
+// Entries are refreshed after ttl; if a refresh fails the stale entry is served instead.
+type PostCache struct {
+	ttl   time.Duration
+	fetch func(locale, slug string) (PostBody, error)
+
+	mu      sync.Mutex
+	entries map[string]postCacheEntry
+}
+
+// postCacheEntry is a cached post body and the time it was fetched.
+type postCacheEntry struct {
+	post      PostBody
+	fetchedAt time.Time
+}
+
+// NewPostCache creates a PostCache that fetches post bodies with fetch and keeps them for ttl.
+func NewPostCache(ttl time.Duration, fetch func(locale, slug string) (PostBody, error)) *PostCache {
+	return &PostCache{
+		ttl:     ttl,
+		fetch:   fetch,
+		entries: make(map[string]postCacheEntry),
+	}
+}
+
+// Get returns the post body for locale and slug, fetching it if it is not cached or has expired.
+// It returns an error if the post cannot be fetched and no stale copy is cached.
+func (c *PostCache) Get(locale, slug string) (PostBody, error) {
+	key := locale + "/" + slug
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok && time.Since(entry.fetchedAt) < c.ttl {
+		return entry.post, nil
+	}
+
+	post, err := c.fetch(locale, slug)
+	if err != nil {
+		if ok {
+			return entry.post, nil
+		}
+		return PostBody{}, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = postCacheEntry{post: post, fetchedAt: time.Now()}
+	c.mu.Unlock()
+
+	return post, nil
+}
+
+// FindPost returns the post with the given locale and slug from the recent or featured posts.
+func (db *Database) FindPost(locale, slug string) (Post, bool) {
+	for _, post := range db.AllPosts() {
+		if post.Locale == locale && post.Slug == slug {
+			return post, true
+		}
+	}
+	return Post{}, false
+}
+
+// AllPosts returns the recent posts followed by the featured posts that are not also recent.
+func (db *Database) AllPosts() []Post {
+	seen := make(map[string]bool)
+	var posts []Post
+	for _, list := range [][]Post{db.Posts.Recent, db.Posts.Featured} {
+		for _, post := range list {
+			key := post.Locale + "/" + post.Slug
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			posts = append(posts, post)
+		}
+	}
+	return posts
+}
+
+// HomePosts returns up to featuredLimit featured posts and up to recentLimit recent posts
+// written in locale, each ordered newest first. Posts shown as featured are left out of
+// the recent posts so the home page does not list them twice.
+func (db *Database) HomePosts(locale string, featuredLimit, recentLimit int) (featured, recent []Post) {
+	featured = limitPosts(sortPostsByDate(filterPostsByLocale(db.Posts.Featured, locale)), featuredLimit)
+
+	shown := make(map[string]bool)
+	for _, post := range featured {
+		shown[post.Locale+"/"+post.Slug] = true
+	}
+	for _, post := range sortPostsByDate(filterPostsByLocale(db.Posts.Recent, locale)) {
+		if !shown[post.Locale+"/"+post.Slug] {
+			recent = append(recent, post)
+		}
+	}
+
+	return featured, limitPosts(recent, recentLimit)
+}
+
+// sortPostsByDate returns a copy of posts ordered by creation time, newest first.
+// Undated posts keep their order after the dated ones.
+func sortPostsByDate(posts []Post) []Post {
+	sorted := append([]Post(nil), posts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt.Time)
+	})
+	return sorted
+}
+
+// limitPosts returns at most n posts.
+func limitPosts(posts []Post, n int) []Post {
+	if len(posts) > n {
+		return posts[:n]
+	}
+	return posts
+}
+
+// BlogHandler handles the HTTP request for the blog index page.
+// It lists every post in the page's locale, linking to the server-rendered post pages.
+func (a *App) BlogHandler(w http.ResponseWriter, r *http.Request) {
+	layout := a.layout(r, a.T(r, "title.blog"))
+	page := BlogPage{
+		Layout: layout,
+		Posts:  filterPostsByLocale(a.Data().AllPosts(), layout.Locale),
+	}
+	a.Render(w, r, http.StatusOK, "templates/blog.html", page)
+}
+
+// PostHandler handles the HTTP request for a single blog post.
+// It fetches the full post body from the blog API through the post cache and renders it.
+// Posts that are not in the content snapshot render the 404 page without querying the API.
+// If the body is unavailable it redirects to the post on the blog website.
+func (a *App) PostHandler(w http.ResponseWriter, r *http.Request) {
+	locale, slug := r.PathValue("locale"), r.PathValue("slug")
+	post, known := a.Data().FindPost(locale, slug)
+	if !known {
+		a.NotFoundHandler(w, r)
+		return
+	}
+
+	body, err := a.PostCache.Get(locale, slug)
+	if err != nil || strings.TrimSpace(body.Body) == "" {
+		if err != nil {
+			a.logger.Printf("Error fetching post %s/%s: %s\n", locale, slug, err)
+		}
+		if post.FullUrl != "" {
+			http.Redirect(w, r, post.FullUrl, http.StatusFound)
+			return
+		}
+		a.NotFoundHandler(w, r)
+		return
+	}
+
+	// The API response is the most complete copy, but fall back to the snapshot for missing fields.
+	if body.Title != "" {
+		post = body.Post
+	}
+	post.Locale, post.Slug = locale, slug
+
+	layout := a.layout(r, post.Title)
+	layout.SEO.Description = orDefault(post.Excerpt, layout.SEO.Description)
+	layout.SEO.Type = "article"
+	layout.SEO.ogImage(a, r, a.postCard(post), ogImagePath("post", post.Locale, post.Slug), post.HeroImage)
+	layout.SEO.StructuredData = []any{a.postSchema(r, layout, post)}
+
+	page := PostPage{
+		Layout: layout,
+		Post:   post,
+		Body:   body.HTML(),
+	}
+	// A post is written in a single language, so the other locales are not alternates of it.
+	page.Alternates = nil
+	a.Render(w, r, http.StatusOK, "templates/post.html", page)
+}
+
+// fetchPostBodyFromAPI fetches a full post from the blog API at {postsUrl}/{locale}/{slug}.
+// It returns a PostBody and an error if any occurred.
+func fetchPostBodyFromAPI(postsUrl, token, locale, slug string) (PostBody, error) {
+	var post PostBody
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	endpoint := strings.TrimSuffix(postsUrl, "/") + "/" + url.PathEscape(locale) + "/" + url.PathEscape(slug)
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return post, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; GoClient/1.1)")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return post, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return post, fmt.Errorf("received non-200 status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return post, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if err = json.Unmarshal(body, &post); err != nil {
+		return post, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	return post, nil
+}
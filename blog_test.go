@@ -0,0 +1,81 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// testPost returns a post in locale created the given number of days after 2024-01-01.
+func testPost(locale, slug string, day int) Post {
+	return Post{Locale: locale, Slug: slug, CreatedAt: Timestamp{time.Date(2024, 1, 1+day, 0, 0, 0, 0, time.UTC)}}
+}
+
+// postSlugs returns the slugs of posts in order.
+func postSlugs(posts []Post) []string {
+	var slugs []string
+	for _, post := range posts {
+		slugs = append(slugs, post.Slug)
+	}
+	return slugs
+}
+
+func TestHomePosts(t *testing.T) {
+	db := Database{Posts: ApiResponse{
+		Featured: []Post{
+			testPost("en", "old-featured", 1),
+			testPost("en", "new-featured", 9),
+			testPost("zh", "zh-featured", 10),
+			testPost("en", "oldest-featured", 0),
+		},
+		Recent: []Post{
+			testPost("en", "recent-a", 5),
+			testPost("en", "new-featured", 9),
+			testPost("en", "recent-b", 7),
+			testPost("en", "old-featured", 1),
+			testPost("en", "recent-c", 3),
+			{Locale: "en", Slug: "undated"},
+			testPost("zh", "zh-recent", 8),
+		},
+	}}
+
+	tests := []struct {
+		name                       string
+		locale                     string
+		featuredLimit, recentLimit int
+		wantFeatured, wantRecent   []string
+	}{
+		{"featured are left out of recent", "en", 2, 10, []string{"new-featured", "old-featured"}, []string{"recent-b", "recent-a", "recent-c", "undated"}},
+		{"featured beyond the limit stay in recent", "en", 1, 10, []string{"new-featured"}, []string{"recent-b", "recent-a", "recent-c", "old-featured", "undated"}},
+		{"limits", "en", 3, 2, []string{"new-featured", "old-featured", "oldest-featured"}, []string{"recent-b", "recent-a"}},
+		{"locale", "zh", 3, 3, []string{"zh-featured"}, []string{"zh-recent"}},
+		{"no featured posts", "en", 0, 3, nil, []string{"new-featured", "recent-b", "recent-a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			featured, recent := db.HomePosts(tt.locale, tt.featuredLimit, tt.recentLimit)
+			if got := postSlugs(featured); !slices.Equal(got, tt.wantFeatured) {
+				t.Errorf("featured = %v, want %v", got, tt.wantFeatured)
+			}
+			if got := postSlugs(recent); !slices.Equal(got, tt.wantRecent) {
+				t.Errorf("recent = %v, want %v", got, tt.wantRecent)
+			}
+		})
+	}
+}
+
+func TestAllPosts(t *testing.T) {
+	db := Database{Posts: ApiResponse{
+		Recent:   []Post{testPost("en", "a", 1), testPost("en", "b", 2), testPost("zh", "a", 1)},
+		Featured: []Post{testPost("en", "b", 2), testPost("en", "c", 3)},
+	}}
+
+	var got []string
+	for _, post := range db.AllPosts() {
+		got = append(got, post.Locale+"/"+post.Slug)
+	}
+	want := []string{"en/a", "en/b", "zh/a", "en/c"}
+	if !slices.Equal(got, want) {
+		t.Errorf("AllPosts = %v, want %v", got, want)
+	}
+}
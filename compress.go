@@ -0,0 +1,194 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compressionMiddleware gzip-compresses responses for clients that accept gzip.
+// Only text, JSON, XML and JavaScript responses of at least cfg.MinSize bytes are compressed:
+// images and fonts are already compressed, and small responses gain less than the gzip
+// header costs. Responses that already have a Content-Encoding, such as precompressed static
+// files, partial content and responses marked no-transform are passed through unchanged.
+// Brotli is not offered because the standard library has no brotli encoder.
+func compressionMiddleware(cfg CompressionConfig, next http.Handler) http.Handler {
+	pool := &sync.Pool{New: func() any {
+		gz, _ := gzip.NewWriterLevel(io.Discard, cfg.Level)
+		return gz
+	}}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressResponseWriter{ResponseWriter: w, pool: pool, minSize: cfg.MinSize, status: http.StatusOK}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows a gzip-encoded response.
+func acceptsGzip(header string) bool {
+	gzipQ, anyQ := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case "gzip", "x-gzip":
+			gzipQ = q
+		case "*":
+			anyQ = q
+		}
+	}
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return anyQ > 0
+}
+
+// compressible reports whether responses of contentType are worth compressing.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/json",
+		mediaType == "application/xml",
+		mediaType == "application/javascript":
+		return true
+	}
+	return false
+}
+
+// compressResponseWriter buffers the start of a response until it knows whether to compress it:
+// once minSize bytes have been written the response is gzip-compressed, and if the handler
+// finishes with less, or the response is not compressible, it is written unchanged.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	pool    *sync.Pool
+	minSize int
+	status  int          // status is the status code the handler set.
+	buf     []byte       // buf holds the body written before the decision was made.
+	decided bool         // decided is set once the header has been sent.
+	gz      *gzip.Writer // gz compresses the body, nil if the response is not compressed.
+}
+
+// WriteHeader records the status code, which is sent once it is known whether to compress.
+func (w *compressResponseWriter) WriteHeader(status int) {
+	if !w.decided {
+		w.status = status
+	}
+}
+
+// Write buffers p until the response is known to be compressible and at least minSize bytes long.
+func (w *compressResponseWriter) Write(p []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if !w.shouldCompress() {
+		w.start(false)
+	} else if len(w.buf) >= w.minSize {
+		w.start(true)
+	}
+	return len(p), nil
+}
+
+// shouldCompress reports whether the response can be compressed, based on its status and header.
+// A missing content type is sniffed from the buffered body, as net/http would.
+func (w *compressResponseWriter) shouldCompress() bool {
+	h := w.Header()
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified ||
+		w.status == http.StatusPartialContent || h.Get("Content-Encoding") != "" ||
+		strings.Contains(h.Get("Cache-Control"), "no-transform") {
+		return false
+	}
+	if h.Get("Content-Type") == "" {
+		if len(w.buf) == 0 {
+			return true
+		}
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	return compressible(h.Get("Content-Type"))
+}
+
+// start sends the header, compressed or not, followed by the buffered body.
+// A compressed response has no Content-Length or byte ranges, and its strong ETag is made weak,
+// as the compressed bytes differ from the representation the ETag was computed for.
+func (w *compressResponseWriter) start(compress bool) {
+	w.decided = true
+	if compress {
+		h := w.Header()
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			h.Set("ETag", "W/"+etag)
+		}
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if len(w.buf) > 0 {
+		if w.gz != nil {
+			w.gz.Write(w.buf)
+		} else {
+			w.ResponseWriter.Write(w.buf)
+		}
+	}
+	w.buf = nil
+}
+
+// Flush sends the response written so far, compressing it if it is compressible whatever its size.
+func (w *compressResponseWriter) Flush() {
+	if !w.decided {
+		w.start(w.shouldCompress())
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Close finishes the response: a response shorter than minSize is written uncompressed,
+// and a compressed one has its gzip stream completed.
+func (w *compressResponseWriter) Close() error {
+	if !w.decided {
+		w.start(false)
+	}
+	if w.gz == nil {
+		return nil
+	}
+	err := w.gz.Close()
+	w.gz.Reset(io.Discard)
+	w.pool.Put(w.gz)
+	w.gz = nil
+	return err
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (w *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
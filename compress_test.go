@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"GZIP", true},
+		{"x-gzip", true},
+		{"deflate, gzip;q=0.8", true},
+		{"br, deflate", false},
+		{"gzip;q=0", false},
+		{"gzip; q=0", false},
+		{"*", true},
+		{"*;q=0", false},
+		{"gzip;q=0, *", false},
+		{"identity;q=1, *;q=0.5", true},
+	}
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.header); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %t, want %t", tt.header, got, tt.want)
+		}
+	}
+}
+
+// gunzip decompresses data, failing the test if it is not valid gzip.
+func gunzip(t *testing.T, data []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %s", err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("reading gzip body: %s", err)
+	}
+	return string(out)
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	large := strings.Repeat("<p>hello</p>", 200)
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		header         map[string]string
+		status         int
+		body           string
+		compressed     bool
+	}{
+		{"large HTML", "gzip", "text/html; charset=utf-8", nil, http.StatusOK, large, true},
+		{"sniffed HTML", "gzip", "", nil, http.StatusOK, "<!DOCTYPE html>" + large, true},
+		{"JSON", "gzip", "application/problem+json", nil, http.StatusNotFound, strings.Repeat(`{"a":1}`, 300), true},
+		{"client without gzip", "", "text/html", nil, http.StatusOK, large, false},
+		{"client refusing gzip", "gzip;q=0", "text/html", nil, http.StatusOK, large, false},
+		{"small response", "gzip", "text/html", nil, http.StatusOK, "<p>hello</p>", false},
+		{"image", "gzip", "image/png", nil, http.StatusOK, large, false},
+		{"already encoded", "gzip", "text/css", map[string]string{"Content-Encoding": "gzip"}, http.StatusOK, large, false},
+		{"no-transform", "gzip", "text/html", map[string]string{"Cache-Control": "no-transform"}, http.StatusOK, large, false},
+		{"partial content", "gzip", "text/html", nil, http.StatusPartialContent, large, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := compressionMiddleware(CompressionConfig{MinSize: 1024, Level: 6}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				for key, value := range tt.header {
+					w.Header().Set(key, value)
+				}
+				w.Header().Set("ETag", `"abc"`)
+				w.WriteHeader(tt.status)
+				// Write in small chunks, so the decision is made part way through the body.
+				for body := tt.body; body != ""; {
+					n := min(len(body), 100)
+					io.WriteString(w, body[:n])
+					body = body[n:]
+				}
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+			compressed := w.Header().Get("Content-Encoding") == "gzip" && tt.header["Content-Encoding"] == ""
+			if compressed != tt.compressed {
+				t.Fatalf("compressed = %t, want %t", compressed, tt.compressed)
+			}
+			if !compressed {
+				if w.Body.String() != tt.body {
+					t.Errorf("uncompressed body differs from the handler's")
+				}
+				if got := w.Header().Get("ETag"); got != `"abc"` {
+					t.Errorf("ETag = %s, want \"abc\"", got)
+				}
+				return
+			}
+			if got := gunzip(t, w.Body.Bytes()); got != tt.body {
+				t.Errorf("decompressed body differs from the handler's")
+			}
+			if got := w.Header().Get("ETag"); got != `W/"abc"` {
+				t.Errorf("ETag = %s, want W/\"abc\"", got)
+			}
+		})
+	}
+}
+
+func TestStaticPrecompressed(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	io.WriteString(zw, "body{color:red}")
+	zw.Close()
+
+	modified := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	static := fstest.MapFS{
+		"css/fresh.css":    {Data: []byte("body{color:red}"), ModTime: modified},
+		"css/fresh.css.gz": {Data: gz.Bytes(), ModTime: modified.Add(time.Minute)},
+		"css/stale.css":    {Data: []byte("body{color:blue}"), ModTime: modified.Add(time.Hour)},
+		"css/stale.css.gz": {Data: gz.Bytes(), ModTime: modified},
+		"css/plain.css":    {Data: []byte("body{color:green}"), ModTime: modified},
+	}
+
+	app := newTestApp(t)
+	app.CacheStaticAssets(static)
+	router := NewRouter(http.HandlerFunc(app.NotFoundHandler))
+	router.Handle(http.MethodGet, "/static/{path...}", app.staticHandler(static))
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		precompressed  bool
+		body           string
+	}{
+		{"sibling", "/static/css/fresh.css", "gzip", true, "body{color:red}"},
+		{"client without gzip", "/static/css/fresh.css", "", false, "body{color:red}"},
+		{"stale sibling", "/static/css/stale.css", "gzip", false, "body{color:blue}"},
+		{"no sibling", "/static/css/plain.css", "gzip", false, "body{color:green}"},
+		{"fingerprinted path", app.StaticAssets.URL("css/fresh.css"), "gzip", true, "body{color:red}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %s: status = %d, want %d", tt.path, w.Code, http.StatusOK)
+			}
+			if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/css") {
+				t.Errorf("Content-Type = %q, want text/css", got)
+			}
+
+			precompressed := w.Header().Get("Content-Encoding") == "gzip"
+			if precompressed != tt.precompressed {
+				t.Fatalf("precompressed = %t, want %t", precompressed, tt.precompressed)
+			}
+			body := w.Body.String()
+			if precompressed {
+				body = gunzip(t, w.Body.Bytes())
+			}
+			if body != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
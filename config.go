@@ -0,0 +1,660 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// localeRegex matches the locales accepted in LOCALES, e.g. "en" or "zh-TW".
+var localeRegex = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// referrerPolicies are the values accepted in REFERRER_POLICY.
+var referrerPolicies = []string{
+	"no-referrer", "no-referrer-when-downgrade", "origin", "origin-when-cross-origin",
+	"same-origin", "strict-origin", "strict-origin-when-cross-origin", "unsafe-url",
+}
+
+// Environments the application can run in.
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// Config holds the application configuration.
+// It is loaded once at startup from the environment, an optional .env file and command-line flags.
+type Config struct {
+	Env              string            // Env is the environment the application runs in (APP_ENV).
+	Port             string            // Port is the port the HTTP server listens on (PORT).
+	SiteURL          string            // SiteURL is the public URL of this site, used for absolute links (SITE_URL).
+	SiteConfig       string            // SiteConfig is the path of the site identity file used instead of content/site.json (SITE_CONFIG).
+	Locales          []string          // Locales are the supported locales, the first being the default (LOCALES).
+	BlogURL          string            // BlogURL is the URL of the blog website (BLOG_URL).
+	BlogAPI          string            // BlogAPI is the URL of the blog posts API (BLOG_API).
+	BlogAPIToken     string            // BlogAPIToken is the bearer token for the blog API (BLOG_API_TOKEN).
+	BlogClientID     string            // BlogClientID is the OAuth client ID for the blog (BLOG_CLIENT_ID).
+	BlogClientSecret string            // BlogClientSecret is the OAuth client secret for the blog (BLOG_CLIENT_SECRET).
+	ProjectsURL      string            // ProjectsURL is the URL of the projects website (PROJECTS_URL).
+	ProjectsAPI      string            // ProjectsAPI is the URL of the projects API (PROJECTS_API).
+	ProjectsAPIKey   string            // ProjectsAPIKey is the key for the projects API (PROJECT_API_KEY).
+	AboutAPI         string            // AboutAPI is the URL the about page content is fetched from instead of content/about.json (ABOUT_API).
+	AboutConfig      string            // AboutConfig is the path of the about page content file used instead of content/about.json (ABOUT_CONFIG).
+	AssetsDir        string            // AssetsDir is the directory templates and static assets are read from instead of the embedded copies (ASSETS_DIR).
+	SMTP             SMTPConfig        // SMTP holds the settings used to send contact form emails.
+	Log              LogConfig         // Log holds the log file rotation settings.
+	Home             HomeConfig        // Home holds the settings of the home page.
+	Sitemap          SitemapConfig     // Sitemap holds the settings of /sitemap.xml.
+	Robots           RobotsConfig      // Robots holds the settings of /robots.txt.
+	Images           ImageConfig       // Images holds the settings of the image proxy.
+	Compression      CompressionConfig // Compression holds the settings of response compression.
+	Security         SecurityConfig    // Security holds the security headers sent with every response.
+	AllowedHosts     []string          // AllowedHosts are the host names the site is served under, empty to allow any (ALLOWED_HOSTS).
+	TLS              TLSConfig         // TLS holds the settings of TLS termination by the server itself.
+}
+
+// SMTPConfig holds the settings used to send contact form emails.
+type SMTPConfig struct {
+	Host     string // Host is the SMTP server host (EMAIL_SMTP_HOST).
+	Port     string // Port is the SMTP server port (EMAIL_SMTP_PORT).
+	From     string // From is the account used to authenticate with the SMTP server (EMAIL_FROM).
+	Password string // Password is the password of the From account (EMAIL_PASSWORD).
+	To       string // To is the address contact form submissions are sent to (EMAIL_TO).
+}
+
+// LogConfig holds the log file rotation settings.
+type LogConfig struct {
+	MaxSizeMB        int  // MaxSizeMB is the size in megabytes after which the log is rotated (LOG_MAX_SIZE_MB).
+	MaxAgeDays       int  // MaxAgeDays is the age in days after which the log is rotated (LOG_MAX_AGE_DAYS).
+	MaxBackups       int  // MaxBackups is the number of rotated logs to keep (LOG_MAX_BACKUPS).
+	MaxBackupAgeDays int  // MaxBackupAgeDays is the number of days rotated logs are kept, 0 keeps them (LOG_MAX_BACKUP_AGE_DAYS).
+	Compress         bool // Compress indicates whether rotated logs are gzip compressed (LOG_COMPRESS).
+}
+
+// HomeConfig holds the settings of the home page.
+type HomeConfig struct {
+	FeaturedPosts int // FeaturedPosts is the number of featured posts shown, 0 hides the section (HOME_FEATURED_POSTS).
+	RecentPosts   int // RecentPosts is the number of recent posts shown, 0 hides the section (HOME_RECENT_POSTS).
+}
+
+// SitemapConfig holds the settings of /sitemap.xml.
+type SitemapConfig struct {
+	MaxURLs int // MaxURLs is the number of pages per sitemap file, above which a sitemap index is served (SITEMAP_MAX_URLS).
+}
+
+// RobotsConfig holds the settings of /robots.txt.
+type RobotsConfig struct {
+	Index    bool     // Index indicates whether crawlers may index the site, true by default in production (ROBOTS_INDEX).
+	Disallow []string // Disallow are the paths crawlers may not visit when indexing is allowed (ROBOTS_DISALLOW).
+}
+
+// ImageConfig holds the settings of the image proxy.
+type ImageConfig struct {
+	Hosts       []string // Hosts are the hosts the image proxy may fetch images from (IMAGE_PROXY_HOSTS).
+	CacheMaxMB  int      // CacheMaxMB is the size in megabytes of cached variants above which the least recently used are removed (IMAGE_CACHE_MAX_MB).
+	Concurrency int      // Concurrency is the number of images resized at the same time (IMAGE_PROXY_CONCURRENCY).
+}
+
+// CompressionConfig holds the settings of response compression.
+type CompressionConfig struct {
+	MinSize int // MinSize is the size in bytes below which responses are sent uncompressed (COMPRESS_MIN_SIZE).
+	Level   int // Level is the gzip compression level, from 1 (fastest) to 9 (smallest) (COMPRESS_LEVEL).
+}
+
+// SecurityConfig holds the security headers sent with every response.
+type SecurityConfig struct {
+	HSTSMaxAge            int    // HSTSMaxAge is the Strict-Transport-Security max-age in seconds, 0 disables it; a year by default in production (HSTS_MAX_AGE).
+	ContentSecurityPolicy string // ContentSecurityPolicy is the Content-Security-Policy, {nonce} being replaced by the request's nonce; empty disables it (CSP_POLICY).
+	CSPReportOnly         bool   // CSPReportOnly reports violations of the policy without enforcing it, true by default outside production (CSP_REPORT_ONLY).
+	ReferrerPolicy        string // ReferrerPolicy is the Referrer-Policy (REFERRER_POLICY).
+	PermissionsPolicy     string // PermissionsPolicy is the Permissions-Policy (PERMISSIONS_POLICY).
+}
+
+// TLSConfig holds the settings of TLS termination by the server itself, instead of a reverse proxy.
+type TLSConfig struct {
+	Mode          string // Mode is "files" to serve CertFile, "acme" to obtain certificates for ALLOWED_HOSTS, or empty to serve plain HTTP (TLS_MODE).
+	Port          string // Port is the port the HTTPS server listens on, PORT then redirecting to it (TLS_PORT).
+	CertFile      string // CertFile is the PEM certificate chain served in files mode (TLS_CERT_FILE).
+	KeyFile       string // KeyFile is the PEM private key of CertFile (TLS_KEY_FILE).
+	ACMEDirectory string // ACMEDirectory is the directory URL of the ACME CA (ACME_DIRECTORY).
+	ACMEEmail     string // ACMEEmail is the contact address registered with the ACME CA (ACME_EMAIL).
+	ACMECARoot    string // ACMECARoot is a PEM file of extra CA certificates trusted for the ACME directory, e.g. Pebble's (ACME_CA_ROOT).
+	ACMEHTTPPort  string // ACMEHTTPPort is the port the ACME CA connects to for http-01 challenges, which PORT must be (ACME_HTTP_PORT).
+}
+
+// Configured reports whether all the settings required to send email are present.
+func (s SMTPConfig) Configured() bool {
+	return s.Host != "" && s.Port != "" && s.From != "" && s.To != ""
+}
+
+// IsProduction reports whether the application runs in production.
+func (c Config) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+// configSource resolves configuration values from the environment,
+// falling back to the values read from a .env file.
+// Parse errors are collected so they can be reported together.
+type configSource struct {
+	dotenv map[string]string
+	errs   []error
+}
+
+// lookup returns the value of key from the environment or the .env file.
+func (s *configSource) lookup(key string) (string, bool) {
+	if value, ok := os.LookupEnv(key); ok {
+		return value, true
+	}
+	value, ok := s.dotenv[key]
+	return value, ok
+}
+
+// String returns the value of key, or fallback if it is unset or empty.
+func (s *configSource) String(key, fallback string) string {
+	value, _ := s.lookup(key)
+	return orDefault(strings.TrimSpace(value), fallback)
+}
+
+// Int returns the integer value of key, or fallback if it is unset or empty.
+func (s *configSource) Int(key string, fallback int) int {
+	value := s.String(key, "")
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s must be an integer, got %q", key, value))
+		return fallback
+	}
+	return n
+}
+
+// Bool returns the boolean value of key, or fallback if it is unset or empty.
+func (s *configSource) Bool(key string, fallback bool) bool {
+	value := s.String(key, "")
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s must be a boolean, got %q", key, value))
+		return fallback
+	}
+	return b
+}
+
+// LoadConfig loads the configuration from the environment, the .env file and the given command-line arguments.
+// Flags take precedence over environment variables, which take precedence over the .env file.
+// It returns the configuration, the remaining non-flag arguments and an error if the flags,
+// the .env file or any value could not be parsed. The configuration is not validated.
+func LoadConfig(args []string) (Config, []string, error) {
+	var cfg Config
+
+	fs := flag.NewFlagSet("portfolio", flag.ContinueOnError)
+	envFile := fs.String("env-file", ".env", "path to an optional .env file")
+	env := fs.String("env", "", "environment to run in: development or production (APP_ENV)")
+	port := fs.String("port", "", "port to listen on (PORT)")
+	blogURL := fs.String("blog-url", "", "URL of the blog website (BLOG_URL)")
+	blogAPI := fs.String("blog-api", "", "URL of the blog posts API (BLOG_API)")
+	projectsURL := fs.String("projects-url", "", "URL of the projects website (PROJECTS_URL)")
+	projectsAPI := fs.String("projects-api", "", "URL of the projects API (PROJECTS_API)")
+	assetsDir := fs.String("assets-dir", "", "serve templates and static assets from this directory instead of the embedded copies (ASSETS_DIR)")
+	if err := fs.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+
+	envFileSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "env-file" {
+			envFileSet = true
+		}
+	})
+
+	dotenv, err := readDotEnv(*envFile)
+	if err != nil && (envFileSet || !os.IsNotExist(err)) {
+		return cfg, nil, fmt.Errorf("could not read %s: %w", *envFile, err)
+	}
+
+	src := &configSource{dotenv: dotenv}
+	cfg = Config{
+		Env:              src.String("APP_ENV", EnvDevelopment),
+		Port:             src.String("PORT", "5050"),
+		SiteURL:          src.String("SITE_URL", ""),
+		SiteConfig:       src.String("SITE_CONFIG", ""),
+		Locales:          splitList(src.String("LOCALES", "en,zh")),
+		BlogURL:          src.String("BLOG_URL", "http://localhost:8000"),
+		BlogAPI:          src.String("BLOG_API", "http://localhost:8000/api/posts"),
+		BlogAPIToken:     src.String("BLOG_API_TOKEN", ""),
+		BlogClientID:     src.String("BLOG_CLIENT_ID", ""),
+		BlogClientSecret: src.String("BLOG_CLIENT_SECRET", ""),
+		ProjectsURL:      src.String("PROJECTS_URL", "http://localhost:8000/projects"),
+		ProjectsAPI:      src.String("PROJECTS_API", "http://localhost:8000/api/projects"),
+		ProjectsAPIKey:   src.String("PROJECT_API_KEY", ""),
+		AboutAPI:         src.String("ABOUT_API", ""),
+		AboutConfig:      src.String("ABOUT_CONFIG", ""),
+		AssetsDir:        src.String("ASSETS_DIR", ""),
+		SMTP: SMTPConfig{
+			// SMTP_HOST and SMTP_PORT are the names used before they were aligned with .env.example.
+			Host:     src.String("EMAIL_SMTP_HOST", src.String("SMTP_HOST", "")),
+			Port:     src.String("EMAIL_SMTP_PORT", src.String("SMTP_PORT", "")),
+			From:     src.String("EMAIL_FROM", ""),
+			Password: src.String("EMAIL_PASSWORD", ""),
+			To:       src.String("EMAIL_TO", ""),
+		},
+		Log: LogConfig{
+			MaxSizeMB:        src.Int("LOG_MAX_SIZE_MB", 10),
+			MaxAgeDays:       src.Int("LOG_MAX_AGE_DAYS", 30),
+			MaxBackups:       src.Int("LOG_MAX_BACKUPS", 5),
+			MaxBackupAgeDays: src.Int("LOG_MAX_BACKUP_AGE_DAYS", 90),
+			Compress:         src.Bool("LOG_COMPRESS", true),
+		},
+		Home: HomeConfig{
+			FeaturedPosts: src.Int("HOME_FEATURED_POSTS", 3),
+			RecentPosts:   src.Int("HOME_RECENT_POSTS", 6),
+		},
+		Sitemap: SitemapConfig{
+			MaxURLs: src.Int("SITEMAP_MAX_URLS", 50000),
+		},
+		Images: ImageConfig{
+			Hosts:       splitList(strings.ToLower(src.String("IMAGE_PROXY_HOSTS", "file.swayechateau.com,swayechateau.com"))),
+			CacheMaxMB:  src.Int("IMAGE_CACHE_MAX_MB", 512),
+			Concurrency: src.Int("IMAGE_PROXY_CONCURRENCY", 2),
+		},
+		Robots: RobotsConfig{
+			Disallow: splitList(src.String("ROBOTS_DISALLOW", "")),
+		},
+		Compression: CompressionConfig{
+			MinSize: src.Int("COMPRESS_MIN_SIZE", 1024),
+			Level:   src.Int("COMPRESS_LEVEL", 6),
+		},
+		AllowedHosts: splitList(strings.ToLower(src.String("ALLOWED_HOSTS", ""))),
+		TLS: TLSConfig{
+			Mode:          src.String("TLS_MODE", ""),
+			Port:          src.String("TLS_PORT", "443"),
+			CertFile:      src.String("TLS_CERT_FILE", ""),
+			KeyFile:       src.String("TLS_KEY_FILE", ""),
+			ACMEDirectory: src.String("ACME_DIRECTORY", "https://acme-v02.api.letsencrypt.org/directory"),
+			ACMEEmail:     src.String("ACME_EMAIL", ""),
+			ACMECARoot:    src.String("ACME_CA_ROOT", ""),
+			ACMEHTTPPort:  src.String("ACME_HTTP_PORT", "80"),
+		},
+		Security: SecurityConfig{
+			ContentSecurityPolicy: src.String("CSP_POLICY", defaultContentSecurityPolicy),
+			ReferrerPolicy:        src.String("REFERRER_POLICY", "strict-origin-when-cross-origin"),
+			PermissionsPolicy:     src.String("PERMISSIONS_POLICY", "camera=(), microphone=(), geolocation=(), payment=(), usb=(), browsing-topics=()"),
+		},
+	}
+
+	cfg.Env = orDefault(*env, cfg.Env)
+	cfg.Port = orDefault(*port, cfg.Port)
+	cfg.BlogURL = orDefault(*blogURL, cfg.BlogURL)
+	cfg.BlogAPI = orDefault(*blogAPI, cfg.BlogAPI)
+	cfg.ProjectsURL = orDefault(*projectsURL, cfg.ProjectsURL)
+	cfg.ProjectsAPI = orDefault(*projectsAPI, cfg.ProjectsAPI)
+	cfg.AssetsDir = orDefault(*assetsDir, cfg.AssetsDir)
+
+	// Only production is indexed by default, so staging and development sites stay out of search results.
+	cfg.Robots.Index = src.Bool("ROBOTS_INDEX", cfg.IsProduction())
+
+	// Browsers are only told to insist on HTTPS in production, and the policy is only enforced there,
+	// so violations show up in the log during development without breaking pages.
+	hstsMaxAge := 0
+	if cfg.IsProduction() {
+		hstsMaxAge = 31536000
+	}
+	cfg.Security.HSTSMaxAge = src.Int("HSTS_MAX_AGE", hstsMaxAge)
+	cfg.Security.CSPReportOnly = src.Bool("CSP_REPORT_ONLY", !cfg.IsProduction())
+
+	// When the server terminates TLS itself the public URL is known from the first allowed host,
+	// so SITE_URL only has to be set behind a reverse proxy.
+	if cfg.SiteURL == "" && cfg.TLS.Mode != "" && len(cfg.AllowedHosts) > 0 {
+		cfg.SiteURL = "https://" + cfg.AllowedHosts[0]
+		if cfg.TLS.Port != "443" {
+			cfg.SiteURL += ":" + cfg.TLS.Port
+		}
+	}
+
+	// In development, serve from the working directory when it is a checkout
+	// so template and asset edits show up without rebuilding.
+	if cfg.AssetsDir == "" && cfg.Env == EnvDevelopment {
+		if info, err := os.Stat("templates"); err == nil && info.IsDir() {
+			cfg.AssetsDir = "."
+		}
+	}
+
+	return cfg, fs.Args(), errors.Join(src.errs...)
+}
+
+// Validate checks the configuration and returns an error listing every problem found.
+// SMTP settings, the blog API token and the site URL are only required in production.
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.Env != EnvDevelopment && c.Env != EnvProduction {
+		errs = append(errs, fmt.Errorf("APP_ENV must be %q or %q, got %q", EnvDevelopment, EnvProduction, c.Env))
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be a number between 1 and 65535, got %q", c.Port))
+	}
+
+	urls := []struct{ key, value string }{
+		{"BLOG_URL", c.BlogURL},
+		{"BLOG_API", c.BlogAPI},
+		{"PROJECTS_URL", c.ProjectsURL},
+		{"PROJECTS_API", c.ProjectsAPI},
+	}
+	for _, u := range urls {
+		if err := validateURL(u.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s %w", u.key, err))
+		}
+	}
+
+	if c.SiteURL != "" {
+		if err := validateURL(c.SiteURL); err != nil {
+			errs = append(errs, fmt.Errorf("SITE_URL %w", err))
+		}
+	}
+
+	if c.AboutAPI != "" {
+		if err := validateURL(c.AboutAPI); err != nil {
+			errs = append(errs, fmt.Errorf("ABOUT_API %w", err))
+		}
+	}
+
+	if c.AboutConfig != "" {
+		if info, err := os.Stat(c.AboutConfig); err != nil || info.IsDir() {
+			errs = append(errs, fmt.Errorf("ABOUT_CONFIG %q must be a file", c.AboutConfig))
+		}
+	}
+
+	if c.SiteConfig != "" {
+		if info, err := os.Stat(c.SiteConfig); err != nil || info.IsDir() {
+			errs = append(errs, fmt.Errorf("SITE_CONFIG %q must be a file", c.SiteConfig))
+		}
+	}
+
+	if len(c.Locales) == 0 {
+		errs = append(errs, errors.New("LOCALES must list at least one locale"))
+	}
+	for _, locale := range c.Locales {
+		if !localeRegex.MatchString(locale) {
+			errs = append(errs, fmt.Errorf("LOCALES must contain locales such as en or zh-TW, got %q", locale))
+		}
+	}
+
+	if c.AssetsDir != "" {
+		for _, dir := range []string{"templates", "static", "locales", "content"} {
+			if info, err := os.Stat(filepath.Join(c.AssetsDir, dir)); err != nil || !info.IsDir() {
+				errs = append(errs, fmt.Errorf("ASSETS_DIR %q must contain a %s directory", c.AssetsDir, dir))
+			}
+		}
+	}
+
+	if c.SMTP.Port != "" {
+		if _, err := strconv.Atoi(c.SMTP.Port); err != nil {
+			errs = append(errs, fmt.Errorf("EMAIL_SMTP_PORT must be a number, got %q", c.SMTP.Port))
+		}
+	}
+
+	if c.IsProduction() {
+		required := []struct{ key, value string }{
+			{"BLOG_API_TOKEN", c.BlogAPIToken},
+			{"EMAIL_SMTP_HOST", c.SMTP.Host},
+			{"EMAIL_SMTP_PORT", c.SMTP.Port},
+			{"EMAIL_FROM", c.SMTP.From},
+			{"EMAIL_PASSWORD", c.SMTP.Password},
+			{"EMAIL_TO", c.SMTP.To},
+			// Without it absolute links are built from the request's Host header,
+			// which a client can set to any value. With TLS_MODE it defaults to the first of ALLOWED_HOSTS.
+			{"SITE_URL", c.SiteURL},
+		}
+		for _, r := range required {
+			if r.value == "" {
+				errs = append(errs, fmt.Errorf("%s is required in production", r.key))
+			}
+		}
+	}
+
+	if c.Log.MaxSizeMB < 0 || c.Log.MaxAgeDays < 0 || c.Log.MaxBackups < 0 || c.Log.MaxBackupAgeDays < 0 {
+		errs = append(errs, errors.New("LOG_MAX_SIZE_MB, LOG_MAX_AGE_DAYS, LOG_MAX_BACKUPS and LOG_MAX_BACKUP_AGE_DAYS must not be negative"))
+	}
+
+	if c.Home.FeaturedPosts < 0 || c.Home.RecentPosts < 0 {
+		errs = append(errs, errors.New("HOME_FEATURED_POSTS and HOME_RECENT_POSTS must not be negative"))
+	}
+
+	if c.Sitemap.MaxURLs < 1 || c.Sitemap.MaxURLs > 50000 {
+		errs = append(errs, fmt.Errorf("SITEMAP_MAX_URLS must be a number between 1 and 50000, got %d", c.Sitemap.MaxURLs))
+	}
+
+	for _, host := range c.Images.Hosts {
+		if strings.ContainsAny(host, ":/") {
+			errs = append(errs, fmt.Errorf("IMAGE_PROXY_HOSTS must be host names without scheme or port, got %q", host))
+		}
+	}
+
+	if c.Images.CacheMaxMB < 1 {
+		errs = append(errs, fmt.Errorf("IMAGE_CACHE_MAX_MB must be at least 1, got %d", c.Images.CacheMaxMB))
+	}
+
+	if c.Images.Concurrency < 1 {
+		errs = append(errs, fmt.Errorf("IMAGE_PROXY_CONCURRENCY must be at least 1, got %d", c.Images.Concurrency))
+	}
+
+	if c.Compression.MinSize < 0 {
+		errs = append(errs, fmt.Errorf("COMPRESS_MIN_SIZE must not be negative, got %d", c.Compression.MinSize))
+	}
+
+	if c.Compression.Level < 1 || c.Compression.Level > 9 {
+		errs = append(errs, fmt.Errorf("COMPRESS_LEVEL must be a number between 1 and 9, got %d", c.Compression.Level))
+	}
+
+	if c.Security.HSTSMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("HSTS_MAX_AGE must not be negative, got %d", c.Security.HSTSMaxAge))
+	}
+
+	if strings.ContainsAny(c.Security.ContentSecurityPolicy+c.Security.ReferrerPolicy+c.Security.PermissionsPolicy, "\r\n") {
+		errs = append(errs, errors.New("CSP_POLICY, REFERRER_POLICY and PERMISSIONS_POLICY must be on a single line"))
+	}
+
+	for _, policy := range strings.Split(c.Security.ReferrerPolicy, ",") {
+		if !slices.Contains(referrerPolicies, strings.TrimSpace(policy)) {
+			errs = append(errs, fmt.Errorf("REFERRER_POLICY must be a list of referrer policies such as strict-origin-when-cross-origin, got %q", c.Security.ReferrerPolicy))
+			break
+		}
+	}
+
+	for _, host := range c.AllowedHosts {
+		if strings.ContainsAny(host, ":/") {
+			errs = append(errs, fmt.Errorf("ALLOWED_HOSTS must be host names without scheme or port, got %q", host))
+		}
+	}
+
+	switch c.TLS.Mode {
+	case "":
+	case TLSModeFiles, TLSModeACME:
+		if port, err := strconv.Atoi(c.TLS.Port); err != nil || port < 1 || port > 65535 || c.TLS.Port == c.Port {
+			errs = append(errs, fmt.Errorf("TLS_PORT must be a number between 1 and 65535 other than PORT, got %q", c.TLS.Port))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("TLS_MODE must be empty, %q or %q, got %q", TLSModeFiles, TLSModeACME, c.TLS.Mode))
+	}
+
+	if c.TLS.Mode == TLSModeFiles {
+		for _, f := range []struct{ key, value string }{{"TLS_CERT_FILE", c.TLS.CertFile}, {"TLS_KEY_FILE", c.TLS.KeyFile}} {
+			if info, err := os.Stat(f.value); err != nil || info.IsDir() {
+				errs = append(errs, fmt.Errorf("%s %q must be a file when TLS_MODE is %q", f.key, f.value, TLSModeFiles))
+			}
+		}
+	}
+
+	if c.TLS.Mode == TLSModeACME {
+		if len(c.AllowedHosts) == 0 {
+			errs = append(errs, fmt.Errorf("ALLOWED_HOSTS must list the host names to obtain certificates for when TLS_MODE is %q", TLSModeACME))
+		}
+		for _, host := range c.AllowedHosts {
+			if strings.Contains(host, "*") || net.ParseIP(host) != nil {
+				errs = append(errs, fmt.Errorf("ALLOWED_HOSTS must not contain wildcards or IP addresses when TLS_MODE is %q, got %q", TLSModeACME, host))
+			}
+		}
+		// The challenges are answered by the HTTP server on PORT, and CAs only connect to one port,
+		// which is 80 for Let's Encrypt.
+		if c.Port != c.TLS.ACMEHTTPPort {
+			errs = append(errs, fmt.Errorf("PORT must be %s when TLS_MODE is %q, as the ACME CA validates http-01 challenges on port %s (ACME_HTTP_PORT), got %q", c.TLS.ACMEHTTPPort, TLSModeACME, c.TLS.ACMEHTTPPort, c.Port))
+		}
+		if err := validateURL(c.TLS.ACMEDirectory); err != nil {
+			errs = append(errs, fmt.Errorf("ACME_DIRECTORY %w", err))
+		}
+		if c.TLS.ACMECARoot != "" {
+			if info, err := os.Stat(c.TLS.ACMECARoot); err != nil || info.IsDir() {
+				errs = append(errs, fmt.Errorf("ACME_CA_ROOT %q must be a file", c.TLS.ACMECARoot))
+			}
+		}
+	}
+
+	for _, p := range c.Robots.Disallow {
+		if !strings.HasPrefix(p, "/") {
+			errs = append(errs, fmt.Errorf("ROBOTS_DISALLOW paths must start with /, got %q", p))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Print writes the configuration to w as KEY=value lines, redacting secrets.
+func (c Config) Print(w io.Writer) {
+	lines := []struct {
+		key    string
+		value  string
+		secret bool
+	}{
+		{"APP_ENV", c.Env, false},
+		{"PORT", c.Port, false},
+		{"SITE_URL", c.SiteURL, false},
+		{"SITE_CONFIG", c.SiteConfig, false},
+		{"LOCALES", strings.Join(c.Locales, ","), false},
+		{"BLOG_URL", c.BlogURL, false},
+		{"BLOG_API", c.BlogAPI, false},
+		{"BLOG_API_TOKEN", c.BlogAPIToken, true},
+		{"BLOG_CLIENT_ID", c.BlogClientID, false},
+		{"BLOG_CLIENT_SECRET", c.BlogClientSecret, true},
+		{"PROJECTS_URL", c.ProjectsURL, false},
+		{"PROJECTS_API", c.ProjectsAPI, false},
+		{"PROJECT_API_KEY", c.ProjectsAPIKey, true},
+		{"ABOUT_API", c.AboutAPI, false},
+		{"ABOUT_CONFIG", c.AboutConfig, false},
+		{"ASSETS_DIR", c.AssetsDir, false},
+		{"EMAIL_SMTP_HOST", c.SMTP.Host, false},
+		{"EMAIL_SMTP_PORT", c.SMTP.Port, false},
+		{"EMAIL_FROM", c.SMTP.From, false},
+		{"EMAIL_PASSWORD", c.SMTP.Password, true},
+		{"EMAIL_TO", c.SMTP.To, false},
+		{"LOG_MAX_SIZE_MB", strconv.Itoa(c.Log.MaxSizeMB), false},
+		{"LOG_MAX_AGE_DAYS", strconv.Itoa(c.Log.MaxAgeDays), false},
+		{"LOG_MAX_BACKUPS", strconv.Itoa(c.Log.MaxBackups), false},
+		{"LOG_MAX_BACKUP_AGE_DAYS", strconv.Itoa(c.Log.MaxBackupAgeDays), false},
+		{"LOG_COMPRESS", strconv.FormatBool(c.Log.Compress), false},
+		{"HOME_FEATURED_POSTS", strconv.Itoa(c.Home.FeaturedPosts), false},
+		{"HOME_RECENT_POSTS", strconv.Itoa(c.Home.RecentPosts), false},
+		{"SITEMAP_MAX_URLS", strconv.Itoa(c.Sitemap.MaxURLs), false},
+		{"ROBOTS_INDEX", strconv.FormatBool(c.Robots.Index), false},
+		{"ROBOTS_DISALLOW", strings.Join(c.Robots.Disallow, ","), false},
+		{"IMAGE_PROXY_HOSTS", strings.Join(c.Images.Hosts, ","), false},
+		{"IMAGE_CACHE_MAX_MB", strconv.Itoa(c.Images.CacheMaxMB), false},
+		{"IMAGE_PROXY_CONCURRENCY", strconv.Itoa(c.Images.Concurrency), false},
+		{"COMPRESS_MIN_SIZE", strconv.Itoa(c.Compression.MinSize), false},
+		{"COMPRESS_LEVEL", strconv.Itoa(c.Compression.Level), false},
+		{"HSTS_MAX_AGE", strconv.Itoa(c.Security.HSTSMaxAge), false},
+		{"CSP_POLICY", c.Security.ContentSecurityPolicy, false},
+		{"CSP_REPORT_ONLY", strconv.FormatBool(c.Security.CSPReportOnly), false},
+		{"REFERRER_POLICY", c.Security.ReferrerPolicy, false},
+		{"PERMISSIONS_POLICY", c.Security.PermissionsPolicy, false},
+		{"ALLOWED_HOSTS", strings.Join(c.AllowedHosts, ","), false},
+		{"TLS_MODE", c.TLS.Mode, false},
+		{"TLS_PORT", c.TLS.Port, false},
+		{"TLS_CERT_FILE", c.TLS.CertFile, false},
+		{"TLS_KEY_FILE", c.TLS.KeyFile, false},
+		{"ACME_DIRECTORY", c.TLS.ACMEDirectory, false},
+		{"ACME_EMAIL", c.TLS.ACMEEmail, false},
+		{"ACME_CA_ROOT", c.TLS.ACMECARoot, false},
+		{"ACME_HTTP_PORT", c.TLS.ACMEHTTPPort, false},
+	}
+
+	for _, line := range lines {
+		value := line.value
+		if line.secret && value != "" {
+			value = "********"
+		}
+		fmt.Fprintf(w, "%s=%s\n", line.key, value)
+	}
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// validateURL returns an error if value is not an absolute http or https URL.
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("is not a valid URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("must be an absolute http or https URL, got %q", value)
+	}
+	return nil
+}
+
+// readDotEnv reads KEY=VALUE pairs from the file at path.
+// Blank lines and lines starting with # are ignored, an optional "export " prefix is stripped,
+// and values may be wrapped in single or double quotes.
+func readDotEnv(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNumber)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeDotEnv writes content to a .env file in a temporary directory and returns its path.
+func writeDotEnv(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	return path
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name   string
+		dotenv string
+		env    map[string]string
+		args   []string
+		want   string
+	}{
+		{"default", "", nil, nil, "5050"},
+		{".env file", "PORT=6000", nil, nil, "6000"},
+		{"quoted .env value", `export PORT="6001"`, nil, nil, "6001"},
+		{"environment over .env file", "PORT=6000", map[string]string{"PORT": "7000"}, nil, "7000"},
+		{"flag over environment", "PORT=6000", map[string]string{"PORT": "7000"}, []string{"-port", "8000"}, "8000"},
+		{"flag over .env file", "PORT=6000", nil, []string{"-port", "8000"}, "8000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			args := append([]string{"-env-file", writeDotEnv(t, tt.dotenv)}, tt.args...)
+			cfg, _, err := LoadConfig(args)
+			if err != nil {
+				t.Fatalf("LoadConfig: %s", err)
+			}
+			if cfg.Port != tt.want {
+				t.Errorf("Port = %q, want %q", cfg.Port, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	if _, _, err := LoadConfig([]string{"-env-file", filepath.Join(t.TempDir(), "missing.env")}); err == nil {
+		t.Errorf("LoadConfig with a missing -env-file succeeded, want an error")
+	}
+
+	_, _, err := LoadConfig([]string{"-env-file", writeDotEnv(t, "LOG_MAX_SIZE_MB=ten\nLOG_COMPRESS=maybe")})
+	if err == nil {
+		t.Fatalf("LoadConfig with invalid values succeeded, want an error")
+	}
+	for _, want := range []string{"LOG_MAX_SIZE_MB must be an integer", "LOG_COMPRESS must be a boolean"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+
+	if _, _, err := LoadConfig([]string{"-env-file", writeDotEnv(t, "NOT A SETTING")}); err == nil {
+		t.Errorf("LoadConfig with a malformed .env line succeeded, want an error")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid, _, err := LoadConfig([]string{"-env-file", os.DevNull})
+	if err != nil {
+		t.Fatalf("LoadConfig: %s", err)
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate of the default configuration: %s", err)
+	}
+
+	production := valid
+	production.Env = EnvProduction
+	production.BlogAPIToken = "token"
+	production.SiteURL = "https://example.com"
+	production.SMTP = SMTPConfig{Host: "smtp.example.com", Port: "587", From: "from@example.com", Password: "secret", To: "to@example.com"}
+	if err := production.Validate(); err != nil {
+		t.Fatalf("Validate of a complete production configuration: %s", err)
+	}
+
+	tests := []struct {
+		name   string
+		base   Config
+		modify func(*Config)
+		want   string
+	}{
+		{"unknown environment", valid, func(c *Config) { c.Env = "staging" }, "APP_ENV must be"},
+		{"port out of range", valid, func(c *Config) { c.Port = "70000" }, "PORT must be a number"},
+		{"relative blog URL", valid, func(c *Config) { c.BlogAPI = "/api/posts" }, "BLOG_API"},
+		{"invalid site URL", valid, func(c *Config) { c.SiteURL = "example.com" }, "SITE_URL"},
+		{"missing site config", valid, func(c *Config) { c.SiteConfig = "/does/not/exist.json" }, "SITE_CONFIG"},
+		{"negative log size", valid, func(c *Config) { c.Log.MaxSizeMB = -1 }, "must not be negative"},
+		{"missing token in production", production, func(c *Config) { c.BlogAPIToken = "" }, "BLOG_API_TOKEN is required in production"},
+		{"missing SMTP host in production", production, func(c *Config) { c.SMTP.Host = "" }, "EMAIL_SMTP_HOST is required in production"},
+		{"missing site URL in production", production, func(c *Config) { c.SiteURL = "" }, "SITE_URL is required in production"},
+		{"ACME on another port than the CA checks", valid, func(c *Config) {
+			c.TLS.Mode, c.AllowedHosts, c.Port = TLSModeACME, []string{"example.com"}, "5050"
+		}, "PORT must be 80 when TLS_MODE is"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.base
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatalf("Validate succeeded, want an error containing %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not contain %q", err, tt.want)
+			}
+		})
+	}
+
+	acme := valid
+	acme.TLS.Mode, acme.AllowedHosts, acme.Port = TLSModeACME, []string{"example.com"}, "80"
+	if err := acme.Validate(); err != nil {
+		t.Errorf("Validate of ACME on port 80: %s", err)
+	}
+	acme.Port, acme.TLS.ACMEHTTPPort = "5002", "5002"
+	if err := acme.Validate(); err != nil {
+		t.Errorf("Validate of ACME on the CA's configured port: %s", err)
+	}
+
+	// The production-only settings are optional in development.
+	development := production
+	development.Env = EnvDevelopment
+	development.BlogAPIToken, development.SiteURL, development.SMTP = "", "", SMTPConfig{}
+	if err := development.Validate(); err != nil {
+		t.Errorf("Validate of a development configuration without production settings: %s", err)
+	}
+}
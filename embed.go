@@ -0,0 +1,22 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+)
+
+// embeddedFiles holds the templates, static assets, translation catalogs and content compiled into the binary,
+// so the server can run from any directory.
+//
+//go:embed templates static locales content
+var embeddedFiles embed.FS
+
+// assetsFS returns the file system templates and static assets are served from.
+// If dir is empty the embedded copies are used, otherwise the files are read from dir on disk.
+func assetsFS(dir string) fs.FS {
+	if dir == "" {
+		return embeddedFiles
+	}
+	return os.DirFS(dir)
+}
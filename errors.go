@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Problem represents an RFC 9457 problem details object.
+type Problem struct {
+	Type     string `json:"type"`               // Type is a URI identifying the problem type.
+	Title    string `json:"title"`              // Title is a short summary of the problem type.
+	Status   int    `json:"status"`             // Status is the HTTP status code.
+	Detail   string `json:"detail,omitempty"`   // Detail explains this occurrence of the problem.
+	Instance string `json:"instance,omitempty"` // Instance is the path of the request that caused the problem.
+}
+
+// RenderError writes an error response with the given status.
+// Clients that prefer JSON receive RFC 9457 problem details, every other client
+// receives the HTML error page. If the error page cannot be rendered it falls back to plain text.
+func (a *App) RenderError(w http.ResponseWriter, r *http.Request, status int) {
+	heading, message := http.StatusText(status), http.StatusText(status)
+	if translated, ok := a.Translations.Lookup(a.requestLocaleOf(r).Locale, fmt.Sprintf("error.%d.title", status)); ok {
+		heading = translated
+	}
+	if translated, ok := a.Translations.Lookup(a.requestLocaleOf(r).Locale, fmt.Sprintf("error.%d.message", status)); ok {
+		message = translated
+	}
+
+	if prefersJSON(r) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(Problem{
+			Type:     "about:blank",
+			Title:    http.StatusText(status),
+			Status:   status,
+			Detail:   message,
+			Instance: r.URL.Path,
+		})
+		return
+	}
+
+	page := ErrorPage{
+		Layout:  a.layout(r, heading),
+		Status:  status,
+		Heading: heading,
+		Message: message,
+	}
+	// Error pages are not indexed, so they have no alternates or canonical URL.
+	page.Alternates = nil
+	page.SEO.Canonical, page.SEO.NoIndex = "", true
+
+	buf, err := a.executeTemplate("templates/error.html", page)
+	if err != nil {
+		a.logger.Printf("Error rendering error template for status %d: %s\n", status, err)
+		http.Error(w, message, status)
+		return
+	}
+	defer releaseBuffer(buf)
+
+	writeHTML(w, status, buf)
+}
+
+// NotFoundHandler renders the 404 page. The router uses it for every unknown path.
+func (a *App) NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	a.RenderError(w, r, http.StatusNotFound)
+}
+
+// MethodNotAllowedHandler renders the 405 page.
+// The router sets the Allow header before calling it.
+func (a *App) MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	a.RenderError(w, r, http.StatusMethodNotAllowed)
+}
+
+// recoverMiddleware recovers from panics in next, logs the stack trace
+// and serves the 500 error page. If next had already started its response the page
+// cannot be served, so the connection is aborted and the client sees a truncated response.
+func (a *App) recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &startedResponseWriter{ResponseWriter: w}
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				a.logger.Printf("Panic serving %s %s: %v\n%s", r.Method, r.URL.Path, rec, debug.Stack())
+				if rw.started {
+					panic(http.ErrAbortHandler)
+				}
+				a.RenderError(w, r, http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// startedResponseWriter records whether a handler has started its response,
+// by writing the header, the body or flushing.
+type startedResponseWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+// WriteHeader records that the response has started and sends the header.
+func (w *startedResponseWriter) WriteHeader(status int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records that the response has started and writes p.
+func (w *startedResponseWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush records that the response has started and flushes it.
+func (w *startedResponseWriter) Flush() {
+	w.started = true
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (w *startedResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// prefersJSON reports whether the request's Accept header ranks a JSON media type
+// above HTML. Requests without an Accept header get HTML.
+func prefersJSON(r *http.Request) bool {
+	jsonQ, htmlQ := -1.0, -1.0
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		switch mediaType {
+		case "application/json", "application/problem+json":
+			jsonQ = max(jsonQ, q)
+		case "text/html", "application/xhtml+xml", "*/*", "text/*":
+			htmlQ = max(htmlQ, q)
+		}
+	}
+
+	return jsonQ > 0 && jsonQ > htmlQ
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecoverMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		abort   bool
+	}{
+		{"panic before the response", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			panic("boom")
+		}, false},
+		{"panic after the header", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			panic("boom")
+		}, true},
+		{"panic after the body", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "partial")
+			panic("boom")
+		}, true},
+		{"panic after a flush", func(w http.ResponseWriter, r *http.Request) {
+			http.NewResponseController(w).Flush()
+			panic("boom")
+		}, true},
+		{"aborted handler", func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t)
+			w := httptest.NewRecorder()
+			var rec any
+			func() {
+				defer func() { rec = recover() }()
+				app.recoverMiddleware(tt.handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			}()
+
+			if tt.abort {
+				if rec != http.ErrAbortHandler {
+					t.Fatalf("recovered %v, want http.ErrAbortHandler", rec)
+				}
+				return
+			}
+			if rec != nil {
+				t.Fatalf("recovered %v, want the panic handled", rec)
+			}
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// feedLimit is the maximum number of items in a feed.
+const feedLimit = 50
+
+// FeedItem is a post or project in a feed, independent of the feed format.
+type FeedItem struct {
+	ID        string    // ID is the absolute URL of the item's page, which never changes.
+	Title     string    // Title is the title of the post or project.
+	URL       string    // URL is the absolute URL of the item's page.
+	Summary   string    // Summary is the excerpt of the post or project.
+	Image     string    // Image is the URL of the hero image, if any.
+	Tags      []string  // Tags are the post's category or the project's tags.
+	Author    string    // Author is the name of the author.
+	Published time.Time // Published is when the item was created.
+	Updated   time.Time // Updated is when the item last changed.
+}
+
+// Feed is the content shared by every feed format.
+type Feed struct {
+	Title       string     // Title is the title of the site.
+	Description string     // Description is the site's tagline.
+	Locale      string     // Locale is the locale of the posts in the feed.
+	HomeURL     string     // HomeURL is the absolute URL of the home page.
+	Updated     time.Time  // Updated is when the most recent item last changed.
+	Items       []FeedItem // Items are the posts and projects, most recently changed first.
+}
+
+// rssDocument is an RSS 2.0 feed.
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// rssChannel is the channel of an RSS 2.0 feed.
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Self          rssLink   `xml:"atom:link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+// rssLink is the atom:link element pointing an RSS feed to itself.
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// rssItem is an item of an RSS 2.0 feed.
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	Description string        `xml:"description,omitempty"`
+	Categories  []string      `xml:"category"`
+	PubDate     string        `xml:"pubDate,omitempty"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+}
+
+// rssGUID is the unique identifier of an RSS item.
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// rssEnclosure is the hero image of an RSS item.
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int    `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// atomFeed is an Atom feed.
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang     string      `xml:"xml:lang,attr"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   atomAuthor  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+// atomLink is a link of an Atom feed or entry.
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+// atomAuthor is the author of an Atom feed or entry.
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+// atomEntry is an entry of an Atom feed.
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published,omitempty"`
+	Links      []atomLink     `xml:"link"`
+	Summary    string         `xml:"summary,omitempty"`
+	Author     *atomAuthor    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+// atomCategory is a category of an Atom entry.
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// jsonFeed is a JSON Feed 1.1 document.
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	Language    string         `json:"language"`
+	Authors     []jsonAuthor   `json:"authors"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+// jsonAuthor is an author of a JSON Feed or item.
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+// jsonFeedItem is an item of a JSON Feed.
+type jsonFeedItem struct {
+	ID            string       `json:"id"`
+	URL           string       `json:"url"`
+	Title         string       `json:"title"`
+	Summary       string       `json:"summary,omitempty"`
+	ContentText   string       `json:"content_text"`
+	Image         string       `json:"image,omitempty"`
+	DatePublished string       `json:"date_published,omitempty"`
+	DateModified  string       `json:"date_modified,omitempty"`
+	Authors       []jsonAuthor `json:"authors,omitempty"`
+	Tags          []string     `json:"tags,omitempty"`
+}
+
+// feed builds the feed of the posts in the locale of r and every project.
+func (a *App) feed(r *http.Request) Feed {
+	locale := a.requestLocaleOf(r).Locale
+	prefix := Layout{Prefix: a.localePrefix(r)}
+	site := a.Site.For(locale)
+
+	f := Feed{
+		Title:       site.Title,
+		Description: site.Tagline,
+		Locale:      locale,
+		HomeURL:     a.absoluteURL(r, prefix.Link("/")),
+	}
+
+	db := a.Data()
+	for _, post := range filterPostsByLocale(db.AllPosts(), locale) {
+		link := a.absoluteURL(r, prefix.Link("/blog/"+post.Locale+"/"+post.Slug))
+		item := FeedItem{
+			ID:        link,
+			Title:     post.Title,
+			URL:       link,
+			Summary:   post.Excerpt,
+			Image:     post.HeroImage,
+			Author:    orDefault(post.Author, site.Owner),
+			Published: post.CreatedAt.Time,
+			Updated:   latest(post.UpdatedAt.Time, post.CreatedAt.Time),
+		}
+		if post.Category != "" {
+			item.Tags = []string{post.Category}
+		}
+		f.Items = append(f.Items, item)
+	}
+
+	for _, project := range db.Projects {
+		link := a.absoluteURL(r, prefix.Link("/projects/"+project.Slug))
+		f.Items = append(f.Items, FeedItem{
+			ID:        link,
+			Title:     project.Title,
+			URL:       link,
+			Summary:   project.Excerpt,
+			Image:     project.Hero,
+			Tags:      project.Tags,
+			Author:    site.Owner,
+			Published: project.CreatedAt.Time,
+			Updated:   latest(project.UpdatedAt.Time, project.CreatedAt.Time),
+		})
+	}
+
+	for i, item := range f.Items {
+		if strings.HasPrefix(item.Image, "/") {
+			f.Items[i].Image = a.absoluteURL(r, item.Image)
+		}
+		f.Updated = latest(f.Updated, item.Updated)
+	}
+
+	sort.SliceStable(f.Items, func(i, j int) bool {
+		return f.Items[i].Updated.After(f.Items[j].Updated)
+	})
+	if len(f.Items) > feedLimit {
+		f.Items = f.Items[:feedLimit]
+	}
+	return f
+}
+
+// RSSHandler handles the HTTP request for the RSS 2.0 feed at /feed.xml.
+func (a *App) RSSHandler(w http.ResponseWriter, r *http.Request) {
+	if a.feedNotModified(w, r, "rss") {
+		return
+	}
+	f := a.feed(r)
+
+	doc := rssDocument{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.HomeURL,
+			Self:          rssLink{Href: a.feedURL(r), Rel: "self", Type: "application/rss+xml"},
+			Description:   orDefault(f.Description, f.Title),
+			Language:      f.Locale,
+			LastBuildDate: formatFeedTime(f.Updated, time.RFC1123Z),
+			Items:         []rssItem{},
+		},
+	}
+	for _, item := range f.Items {
+		rss := rssItem{
+			Title:       item.Title,
+			Link:        item.URL,
+			GUID:        rssGUID{Value: item.ID, IsPermaLink: true},
+			Description: item.Summary,
+			Categories:  item.Tags,
+			PubDate:     formatFeedTime(item.Published, time.RFC1123Z),
+		}
+		if item.Published.IsZero() {
+			rss.PubDate = formatFeedTime(item.Updated, time.RFC1123Z)
+		}
+		if item.Image != "" {
+			rss.Enclosure = &rssEnclosure{URL: item.Image, Type: imageType(item.Image)}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, rss)
+	}
+
+	writeXML(w, "application/rss+xml; charset=utf-8", doc)
+}
+
+// AtomHandler handles the HTTP request for the Atom feed at /atom.xml.
+func (a *App) AtomHandler(w http.ResponseWriter, r *http.Request) {
+	if a.feedNotModified(w, r, "atom") {
+		return
+	}
+	f := a.feed(r)
+
+	doc := atomFeed{
+		Lang:     f.Locale,
+		ID:       f.HomeURL,
+		Title:    f.Title,
+		Subtitle: f.Description,
+		Updated:  formatFeedTime(f.Updated, time.RFC3339),
+		Links: []atomLink{
+			{Href: f.HomeURL, Rel: "alternate", Type: "text/html"},
+			{Href: a.feedURL(r), Rel: "self", Type: "application/atom+xml"},
+		},
+		Author: atomAuthor{Name: a.Site.Owner},
+	}
+	// Atom requires an updated date, so an empty feed is dated now.
+	if doc.Updated == "" {
+		doc.Updated = time.Now().UTC().Format(time.RFC3339)
+	}
+	for _, item := range f.Items {
+		entry := atomEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Updated:   orDefault(formatFeedTime(item.Updated, time.RFC3339), doc.Updated),
+			Published: formatFeedTime(item.Published, time.RFC3339),
+			Links:     []atomLink{{Href: item.URL, Rel: "alternate", Type: "text/html"}},
+			Summary:   item.Summary,
+		}
+		if item.Author != a.Site.Owner {
+			entry.Author = &atomAuthor{Name: item.Author}
+		}
+		if item.Image != "" {
+			entry.Links = append(entry.Links, atomLink{Href: item.Image, Rel: "enclosure", Type: imageType(item.Image)})
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	writeXML(w, "application/atom+xml; charset=utf-8", doc)
+}
+
+// JSONFeedHandler handles the HTTP request for the JSON Feed at /feed.json.
+func (a *App) JSONFeedHandler(w http.ResponseWriter, r *http.Request) {
+	if a.feedNotModified(w, r, "json") {
+		return
+	}
+	f := a.feed(r)
+
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.HomeURL,
+		FeedURL:     a.feedURL(r),
+		Description: f.Description,
+		Language:    f.Locale,
+		Authors:     []jsonAuthor{{Name: a.Site.Owner}},
+		Items:       []jsonFeedItem{},
+	}
+	for _, item := range f.Items {
+		entry := jsonFeedItem{
+			ID:            item.ID,
+			URL:           item.URL,
+			Title:         item.Title,
+			Summary:       item.Summary,
+			ContentText:   item.Summary,
+			Image:         item.Image,
+			DatePublished: formatFeedTime(item.Published, time.RFC3339),
+			DateModified:  formatFeedTime(item.Updated, time.RFC3339),
+			Tags:          item.Tags,
+		}
+		if item.Author != a.Site.Owner {
+			entry.Authors = []jsonAuthor{{Name: item.Author}}
+		}
+		doc.Items = append(doc.Items, entry)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		a.logger.Printf("Error encoding JSON feed: %s\n", err)
+		a.RenderError(w, r, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/feed+json; charset=utf-8")
+	w.Write(data)
+}
+
+// feedNotModified sets the ETag of a feed, derived from the content snapshot, the feed format,
+// the locale and the URL the feed is served at. It reports whether the client's copy is current,
+// in which case it has written a 304 Not Modified response.
+func (a *App) feedNotModified(w http.ResponseWriter, r *http.Request, format string) bool {
+	sum := sha256.Sum256([]byte(a.Data().Snapshot() + " " + format + " " + a.requestLocaleOf(r).Locale + " " + a.feedURL(r)))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// feedURL returns the absolute URL of the feed requested by r, including its locale prefix.
+func (a *App) feedURL(r *http.Request) string {
+	return a.absoluteURL(r, a.localePrefix(r)+r.URL.Path)
+}
+
+// formatFeedTime formats t in layout, or returns an empty string if t is zero.
+func formatFeedTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(layout)
+}
+
+// imageType guesses the MIME type of an image from its URL, defaulting to JPEG.
+func imageType(url string) string {
+	path, _, _ := strings.Cut(url, "?")
+	switch {
+	case strings.HasSuffix(strings.ToLower(path), ".png"):
+		return "image/png"
+	case strings.HasSuffix(strings.ToLower(path), ".gif"):
+		return "image/gif"
+	case strings.HasSuffix(strings.ToLower(path), ".webp"):
+		return "image/webp"
+	default:
+		return "image/jpeg"
+	}
+}
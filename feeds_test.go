@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFeedETags(t *testing.T) {
+	app := newTestApp(t)
+	router, err := app.Routes()
+	if err != nil {
+		t.Fatalf("Routes: %s", err)
+	}
+	handler := app.localeMiddleware(router)
+
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	etags := make(map[string]string)
+	for _, path := range []string{"/feed.xml", "/atom.xml", "/feed.json", "/zh/feed.xml"} {
+		w := get(path, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, want %d", path, w.Code, http.StatusOK)
+		}
+		etag := w.Header().Get("ETag")
+		if etag == "" {
+			t.Fatalf("GET %s: no ETag", path)
+		}
+		for other, otherETag := range etags {
+			if etag == otherETag {
+				t.Errorf("%s and %s share the ETag %s", path, other, etag)
+			}
+		}
+		etags[path] = etag
+	}
+
+	etag := etags["/feed.xml"]
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		status      int
+	}{
+		{"same ETag", etag, http.StatusNotModified},
+		{"weak comparison", "W/" + etag, http.StatusNotModified},
+		{"one of several", `"stale", ` + etag, http.StatusNotModified},
+		{"any", "*", http.StatusNotModified},
+		{"other ETag", `"stale"`, http.StatusOK},
+		{"ETag of another format", etags["/atom.xml"], http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get("/feed.xml", tt.ifNoneMatch)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.status == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("304 response has a body of %d bytes", w.Body.Len())
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Errorf("ETag = %s, want %s", got, etag)
+			}
+		})
+	}
+
+	// New content changes the ETag, so clients holding the old one get the new feed.
+	db := *app.Data()
+	db.Posts.Recent = []Post{testPost("en", "hello", 1)}
+	app.data.Store(&db)
+	w := get("/feed.xml", etag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /feed.xml after a content change: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w.Header().Get("ETag") == etag {
+		t.Errorf("ETag did not change with the content")
+	}
+}
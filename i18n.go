@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// localeContextKey is the request context key holding the requestLocale.
+type localeContextKey struct{}
+
+// requestLocale is the locale a request is served in.
+type requestLocale struct {
+	Locale   string // Locale is the locale the page is rendered in.
+	Prefixed bool   // Prefixed indicates whether the locale came from the URL rather than Accept-Language.
+}
+
+// Translations holds a translation catalog for every supported locale.
+// A catalog is a flat JSON object mapping message keys to text in locales/{locale}.json.
+// Messages missing from a catalog fall back to the default locale, then to the key itself.
+type Translations struct {
+	locales  []string // locales are the supported locales, the first being the default.
+	catalogs map[string]map[string]string
+}
+
+// LoadTranslations loads the catalogs for locales from dir within fsys.
+// The first locale is the default. It returns an error if a catalog is missing or invalid,
+// or if a catalog has keys the default catalog does not, which usually means a typo.
+func LoadTranslations(fsys fs.FS, dir string, locales []string) (*Translations, error) {
+	if len(locales) == 0 {
+		return nil, fmt.Errorf("no locales configured")
+	}
+
+	t := &Translations{locales: locales, catalogs: make(map[string]map[string]string)}
+	for _, locale := range locales {
+		data, err := fs.ReadFile(fsys, path.Join(dir, locale+".json"))
+		if err != nil {
+			return nil, fmt.Errorf("error reading catalog for locale %s: %w", locale, err)
+		}
+		catalog := make(map[string]string)
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return nil, fmt.Errorf("error decoding catalog for locale %s: %w", locale, err)
+		}
+		t.catalogs[locale] = catalog
+	}
+
+	defaults := t.catalogs[t.Default()]
+	for _, locale := range locales[1:] {
+		var unknown []string
+		for key := range t.catalogs[locale] {
+			if _, ok := defaults[key]; !ok {
+				unknown = append(unknown, key)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return nil, fmt.Errorf("catalog for locale %s has keys missing from %s: %s", locale, t.Default(), strings.Join(unknown, ", "))
+		}
+	}
+
+	return t, nil
+}
+
+// Default returns the default locale.
+func (t *Translations) Default() string {
+	return t.locales[0]
+}
+
+// Locales returns the supported locales, the default first.
+func (t *Translations) Locales() []string {
+	return append([]string(nil), t.locales...)
+}
+
+// Supported reports whether locale is one of the supported locales.
+func (t *Translations) Supported(locale string) bool {
+	for _, l := range t.locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup returns the message for key in locale, falling back to the default locale.
+func (t *Translations) Lookup(locale, key string) (string, bool) {
+	if message, ok := t.catalogs[locale][key]; ok {
+		return message, true
+	}
+	message, ok := t.catalogs[t.Default()][key]
+	return message, ok
+}
+
+// Translate returns the message for key in locale, formatted with args if any are given.
+// Missing messages are returned as the key so they are easy to spot on the page.
+func (t *Translations) Translate(locale, key string, args ...any) string {
+	message, ok := t.Lookup(locale, key)
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// Negotiate returns the supported locale that best matches an Accept-Language header,
+// or the default locale if none match. A language range such as "zh-CN" also matches
+// the supported locale "zh".
+func (t *Translations) Negotiate(acceptLanguage string) string {
+	best, bestQ := t.Default(), 0.0
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= bestQ {
+			continue
+		}
+
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		for _, locale := range t.locales {
+			language, _, _ := strings.Cut(tag, "-")
+			if tag == strings.ToLower(locale) || language == strings.ToLower(locale) {
+				best, bestQ = locale, q
+				break
+			}
+		}
+	}
+
+	return best
+}
+
+// localeMiddleware determines the locale of each request.
+// Paths starting with a supported locale, e.g. /zh/about, are served in that locale with
+// the prefix removed before routing. Other paths are served in the locale negotiated from
+// the Accept-Language header.
+func (a *App) localeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := requestLocale{}
+
+		first, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if a.Translations.Supported(first) {
+			current = requestLocale{Locale: first, Prefixed: true}
+			r = r.Clone(r.Context())
+			r.URL.Path = "/" + rest
+			r.URL.RawPath = ""
+		} else {
+			current.Locale = a.Translations.Negotiate(r.Header.Get("Accept-Language"))
+			if !strings.HasPrefix(r.URL.Path, "/static/") {
+				w.Header().Add("Vary", "Accept-Language")
+			}
+		}
+
+		w.Header().Set("Content-Language", current.Locale)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), localeContextKey{}, current)))
+	})
+}
+
+// requestLocaleOf returns the locale of r. Requests that did not pass through
+// localeMiddleware, such as those recovered from a panic before routing, are negotiated.
+func (a *App) requestLocaleOf(r *http.Request) requestLocale {
+	if current, ok := r.Context().Value(localeContextKey{}).(requestLocale); ok {
+		return current
+	}
+	return requestLocale{Locale: a.Translations.Negotiate(r.Header.Get("Accept-Language"))}
+}
+
+// localePrefix returns the locale prefix of r's path, e.g. "/zh", or an empty string
+// if the locale was negotiated.
+func (a *App) localePrefix(r *http.Request) string {
+	if current := a.requestLocaleOf(r); current.Prefixed {
+		return "/" + current.Locale
+	}
+	return ""
+}
+
+// T translates key into the locale of r.
+func (a *App) T(r *http.Request, key string, args ...any) string {
+	return a.Translations.Translate(a.requestLocaleOf(r).Locale, key, args...)
+}
+
+// filterPostsByLocale returns the posts written in locale.
+func filterPostsByLocale(posts []Post, locale string) []Post {
+	var filtered []Post
+	for _, post := range posts {
+		if post.Locale == locale {
+			filtered = append(filtered, post)
+		}
+	}
+	return filtered
+}
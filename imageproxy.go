@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// imageWidths are the widths images are resized to. Only these widths are served,
+// so the cache cannot be filled with arbitrary sizes.
+var imageWidths = []int{320, 640, 960, 1280, 1920}
+
+// Limits on the images the proxy accepts.
+const (
+	maxImageBytes  = 20 << 20   // maxImageBytes is the largest source image downloaded.
+	maxImagePixels = 16_000_000 // maxImagePixels is the largest source image decoded, bounding the memory a decode takes.
+	jpegQuality    = 82         // jpegQuality is the quality JPEG variants are encoded with.
+)
+
+// imageLockStripes is the number of mutexes variants are spread over by key.
+const imageLockStripes = 64
+
+// Errors returned by ImageProxy.Get, mapped to response statuses by ImageHandler.
+var (
+	errImageNotAllowed = errors.New("image source is not allowed")
+	errImageWidth      = errors.New("unsupported image width")
+	errImageFetch      = errors.New("error fetching image")
+)
+
+// ImageProxy fetches images from this site's static files and an allowlist of hosts,
+// resizes them to one of imageWidths and re-encodes them as JPEG, or PNG for PNG and GIF
+// sources so transparency is kept. Variants are cached on disk in dir, and the least recently
+// used are removed once the cache outgrows its limit. Only a few images are resized at a time,
+// as decoding one takes tens of megabytes.
+// WebP is not produced because the standard library has no WebP encoder.
+type ImageProxy struct {
+	hosts    []string      // hosts are the hosts remote images may be fetched from.
+	static   fs.FS         // static holds the site's static files, for /static/ sources.
+	dir      string        // dir is the directory variants are cached in.
+	maxBytes int64         // maxBytes is the size of the cache above which variants are removed.
+	sem      chan struct{} // sem holds a slot for every image being fetched and resized.
+	client   *http.Client
+	locks    [imageLockStripes]sync.Mutex // locks serialise work on each variant so it is only produced once.
+
+	mu   sync.Mutex
+	size int64 // size is the total size of the cached variants.
+}
+
+// NewImageProxy creates an ImageProxy configured by cfg, caching variants in dir.
+func NewImageProxy(static fs.FS, cfg ImageConfig, dir string) *ImageProxy {
+	p := &ImageProxy{
+		hosts:    cfg.Hosts,
+		static:   static,
+		dir:      dir,
+		maxBytes: int64(cfg.CacheMaxMB) << 20,
+		sem:      make(chan struct{}, max(1, cfg.Concurrency)),
+	}
+	p.size, _ = cacheSize(dir)
+	// Redirects are only followed to allowed hosts, so the proxy cannot be bounced to internal addresses.
+	p.client = &http.Client{
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 || !p.Allowed(req.URL.String()) {
+				return errImageNotAllowed
+			}
+			return nil
+		},
+	}
+	return p
+}
+
+// Allowed reports whether src can be served through the proxy: a /static/ path,
+// or an http or https URL on one of the allowed hosts.
+func (p *ImageProxy) Allowed(src string) bool {
+	if strings.HasPrefix(src, "/static/") {
+		return true
+	}
+	u, err := url.Parse(src)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return slices.Contains(p.hosts, strings.ToLower(u.Hostname()))
+}
+
+// URL returns the proxy URL of src resized to width, or src itself if it cannot be proxied.
+func (p *ImageProxy) URL(src string, width int) string {
+	if src == "" || !p.Allowed(src) {
+		return src
+	}
+	query := url.Values{"src": {src}, "w": {strconv.Itoa(width)}}
+	return "/img?" + query.Encode()
+}
+
+// Srcset returns a srcset attribute value listing src at every width in imageWidths,
+// or an empty string if src cannot be proxied.
+func (p *ImageProxy) Srcset(src string) string {
+	if src == "" || !p.Allowed(src) {
+		return ""
+	}
+	candidates := make([]string, 0, len(imageWidths))
+	for _, width := range imageWidths {
+		candidates = append(candidates, fmt.Sprintf("%s %dw", p.URL(src, width), width))
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// Get returns the path of the cached variant of src at width and its content type,
+// producing the variant first if it is not cached.
+func (p *ImageProxy) Get(src string, width int) (string, string, error) {
+	if !slices.Contains(imageWidths, width) {
+		return "", "", errImageWidth
+	}
+	if !p.Allowed(src) {
+		return "", "", errImageNotAllowed
+	}
+
+	sum := sha256.Sum256([]byte(src + "\x00" + strconv.Itoa(width)))
+	key := hex.EncodeToString(sum[:16])
+
+	lock := &p.locks[int(sum[0])%imageLockStripes]
+	lock.Lock()
+	defer lock.Unlock()
+
+	for ext, contentType := range map[string]string{".jpg": "image/jpeg", ".png": "image/png"} {
+		name := filepath.Join(p.dir, key+ext)
+		if _, err := os.Stat(name); err == nil {
+			// The modification time records when the variant was last used, for evict.
+			now := time.Now()
+			os.Chtimes(name, now, now)
+			return name, contentType, nil
+		}
+	}
+
+	p.sem <- struct{}{}
+	data, err := p.fetch(src)
+	var encoded []byte
+	var ext, contentType string
+	if err == nil {
+		encoded, ext, contentType, err = resizeImage(data, width)
+	}
+	<-p.sem
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", errImageFetch, err)
+	}
+
+	if err := os.MkdirAll(p.dir, 0o755); err != nil {
+		return "", "", fmt.Errorf("error creating cache directory: %w", err)
+	}
+	name := filepath.Join(p.dir, key+ext)
+	if err := os.WriteFile(name, encoded, 0o644); err != nil {
+		return "", "", fmt.Errorf("error writing image: %w", err)
+	}
+
+	p.mu.Lock()
+	p.size += int64(len(encoded))
+	full := p.size > p.maxBytes
+	p.mu.Unlock()
+	if full {
+		p.evict(name)
+	}
+	return name, contentType, nil
+}
+
+// cacheSize returns the total size of the files in dir.
+func cacheSize(dir string) (int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+	}
+	return size, nil
+}
+
+// evict removes the least recently used variants until the cache is at most
+// three quarters of its limit, so it is not run again on the next write.
+// keep, the variant just produced, is never removed.
+func (p *ImageProxy) evict(keep string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entries, err := os.ReadDir(p.dir)
+	if err != nil {
+		return
+	}
+	var files []fs.FileInfo
+	var size int64
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+			files = append(files, info)
+			size += info.Size()
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+
+	for _, info := range files {
+		if size <= p.maxBytes/4*3 {
+			break
+		}
+		name := filepath.Join(p.dir, info.Name())
+		if name == keep {
+			continue
+		}
+		if err := os.Remove(name); err == nil {
+			size -= info.Size()
+		}
+	}
+	p.size = size
+}
+
+// fetch reads the source image from the static files or the remote host.
+func (p *ImageProxy) fetch(src string) ([]byte, error) {
+	if name, ok := strings.CutPrefix(src, "/static/"); ok {
+		return fs.ReadFile(p.static, name)
+	}
+
+	req, err := http.NewRequest("GET", src, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "image/jpeg, image/png, image/gif")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; GoClient/1.1)")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received non-200 status code %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("image is larger than %d bytes", maxImageBytes)
+	}
+	return data, nil
+}
+
+// resizeImage decodes a JPEG, PNG or GIF image, scales it down to width if it is wider,
+// and encodes it as PNG if the source was PNG or GIF, or as JPEG otherwise.
+// It returns the encoded image, its file extension and its content type.
+func resizeImage(data []byte, width int) ([]byte, string, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("error decoding image: %w", err)
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, "", "", fmt.Errorf("image is larger than %d pixels", maxImagePixels)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("error decoding image: %w", err)
+	}
+
+	var dst image.Image = src
+	if b := src.Bounds(); b.Dx() > width {
+		dst = scaleDown(src, width, max(1, b.Dy()*width/b.Dx()))
+	}
+
+	var buf bytes.Buffer
+	if format == "png" || format == "gif" {
+		if err := png.Encode(&buf, dst); err != nil {
+			return nil, "", "", fmt.Errorf("error encoding image: %w", err)
+		}
+		return buf.Bytes(), ".png", "image/png", nil
+	}
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, "", "", fmt.Errorf("error encoding image: %w", err)
+	}
+	return buf.Bytes(), ".jpg", "image/jpeg", nil
+}
+
+// scaleDown resizes src to width x height by averaging the source pixels each destination
+// pixel covers, which gives smooth results when shrinking. Colours are averaged premultiplied
+// by alpha so transparent edges do not darken. The source is converted to RGBA one band of
+// rows at a time, so no full-size copy of it is held in memory.
+func scaleDown(src image.Image, width, height int) *image.RGBA {
+	b := src.Bounds()
+	band := image.NewRGBA(image.Rect(0, 0, b.Dx(), (b.Dy()+height-1)/height+1))
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*b.Dy()/height, max((y+1)*b.Dy()/height, y*b.Dy()/height+1)
+		draw.Draw(band, image.Rect(0, 0, b.Dx(), y1-y0), src, image.Pt(b.Min.X, b.Min.Y+y0), draw.Src)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*b.Dx()/width, max((x+1)*b.Dx()/width, x*b.Dx()/width+1)
+
+			var r, g, bl, a, n int
+			for sy := 0; sy < y1-y0; sy++ {
+				i := band.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(band.Pix[i])
+					g += int(band.Pix[i+1])
+					bl += int(band.Pix[i+2])
+					a += int(band.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+
+			j := dst.PixOffset(x, y)
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(bl / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// ImageHandler handles the HTTP request for an image through the proxy, /img?src={url}&w={width}.
+// Missing static images are not found, sources outside the allowlist are forbidden,
+// missing or unsupported widths are a bad request and images that cannot be fetched or decoded are a bad gateway.
+func (a *App) ImageHandler(w http.ResponseWriter, r *http.Request) {
+	src := r.URL.Query().Get("src")
+	width, err := strconv.Atoi(r.URL.Query().Get("w"))
+	if err != nil {
+		// A missing or malformed width is unsupported, so Get answers with errImageWidth.
+		width = 0
+	}
+
+	name, contentType, err := a.Images.Get(src, width)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			status = http.StatusNotFound
+		case errors.Is(err, errImageNotAllowed):
+			status = http.StatusForbidden
+		case errors.Is(err, errImageWidth):
+			status = http.StatusBadRequest
+		case errors.Is(err, errImageFetch):
+			status = http.StatusBadGateway
+		}
+		a.logger.Printf("Error serving image %q at width %d: %s\n", src, width, err)
+		a.RenderError(w, r, status)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=604800")
+	w.Header().Set("Content-Type", contentType)
+	http.ServeFile(w, r, name)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestImageHandler(t *testing.T) {
+	app := newTestApp(t)
+	router, err := app.Routes()
+	if err != nil {
+		t.Fatalf("Routes: %s", err)
+	}
+	handler := app.localeMiddleware(router)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"supported width", "/img?src=/static/img/logo-swaye.png&w=320", http.StatusOK},
+		{"missing width", "/img?src=/static/img/logo-swaye.png", http.StatusBadRequest},
+		{"malformed width", "/img?src=/static/img/logo-swaye.png&w=wide", http.StatusBadRequest},
+		{"unsupported width", "/img?src=/static/img/logo-swaye.png&w=100", http.StatusBadRequest},
+		{"missing static image", "/img?src=/static/img/missing.png&w=320", http.StatusNotFound},
+		{"host outside the allowlist", "/img?src=https://example.com/a.png&w=320", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Errorf("GET %s: status = %d, want %d", tt.path, w.Code, tt.status)
+			}
+		})
+	}
+}
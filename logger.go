@@ -0,0 +1,332 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logFileMode is the permission used for the active log file and its rotated backups.
+// Logs can contain form submissions, so they are only readable by the owner.
+const logFileMode = 0600
+
+// rotatedTimeFormat is the timestamp appended to rotated log file names.
+const rotatedTimeFormat = "2006-01-02T15-04-05.000"
+
+// createdSuffix is appended to the log file path to name the file recording when the
+// active log file was created, as file systems do not reliably record creation times.
+const createdSuffix = ".created"
+
+// RotatingFile is an io.WriteCloser that writes to a log file and rotates it
+// once it grows beyond MaxSize or becomes older than MaxAge.
+// Rotated files are renamed with a timestamp suffix, optionally gzip compressed,
+// and pruned so that at most MaxBackups of them, none older than MaxBackupAge, are kept.
+type RotatingFile struct {
+	Path         string        // Path is the path of the active log file.
+	MaxSize      int64         // MaxSize is the size in bytes after which the file is rotated. Zero disables size rotation.
+	MaxAge       time.Duration // MaxAge is the age since its creation after which the file is rotated. Zero disables age rotation.
+	MaxBackups   int           // MaxBackups is the number of rotated files to keep. Zero keeps all of them.
+	MaxBackupAge time.Duration // MaxBackupAge is the time since their rotation after which backups are removed. Zero keeps them.
+	Compress     bool          // Compress indicates whether rotated files are gzip compressed.
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	created time.Time // created is when the active log file was created, read from its createdSuffix file.
+}
+
+// NewRotatingFile opens the log file at path for appending, creating it if needed.
+// It returns an error if the file cannot be opened.
+func NewRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int, maxBackupAge time.Duration, compress bool) (*RotatingFile, error) {
+	rf := &RotatingFile{
+		Path:         path,
+		MaxSize:      maxSize,
+		MaxAge:       maxAge,
+		MaxBackups:   maxBackups,
+		MaxBackupAge: maxBackupAge,
+		Compress:     compress,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// Write writes p to the log file, rotating it first if the write would exceed MaxSize
+// or the file is older than MaxAge.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if rf.shouldRotate(int64(len(p))) {
+		if err := rf.rotate(); err != nil {
+			return 0, fmt.Errorf("could not rotate log file: %w", err)
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Reopen closes and reopens the log file without rotating it.
+// It is used after an external tool such as logrotate has moved the file away.
+func (rf *RotatingFile) Reopen() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file != nil {
+		if err := rf.file.Close(); err != nil {
+			return fmt.Errorf("could not close log file: %w", err)
+		}
+		rf.file = nil
+	}
+	return rf.open()
+}
+
+// Rotate forces a rotation of the log file.
+func (rf *RotatingFile) Rotate() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.rotate()
+}
+
+// Close closes the log file.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
+
+// open opens the log file in append mode and records its current size and creation time.
+func (rf *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(rf.Path), 0700); err != nil {
+		return fmt.Errorf("could not create log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(rf.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, logFileMode)
+	if err != nil {
+		return fmt.Errorf("could not open log file: %w", err)
+	}
+
+	// Tighten permissions on files created by older versions with 0666.
+	if err := file.Chmod(logFileMode); err != nil {
+		file.Close()
+		return fmt.Errorf("could not set log file permissions: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("could not stat log file: %w", err)
+	}
+
+	created, err := rf.creationTime(info.Size())
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("could not record log file creation time: %w", err)
+	}
+
+	rf.file = file
+	rf.size = info.Size()
+	rf.created = created
+	return nil
+}
+
+// creationTime returns when the active log file of the given size was created. An empty
+// file, or one whose creation time was never recorded, is taken to be created now and the
+// time is recorded, so the file's age survives restarts. The modification time cannot be
+// used instead, as every write moves it forward and a busy log would never reach MaxAge.
+func (rf *RotatingFile) creationTime(size int64) (time.Time, error) {
+	path := rf.Path + createdSuffix
+	if size > 0 {
+		if data, err := os.ReadFile(path); err == nil {
+			if created, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data))); err == nil {
+				return created, nil
+			}
+		}
+	}
+
+	created := time.Now()
+	if err := os.WriteFile(path, []byte(created.Format(time.RFC3339Nano)+"\n"), logFileMode); err != nil {
+		return time.Time{}, err
+	}
+	return created, nil
+}
+
+// shouldRotate reports whether writing n more bytes requires a rotation first.
+func (rf *RotatingFile) shouldRotate(n int64) bool {
+	if rf.size == 0 {
+		return false
+	}
+	if rf.MaxSize > 0 && rf.size+n > rf.MaxSize {
+		return true
+	}
+	return rf.MaxAge > 0 && time.Since(rf.created) > rf.MaxAge
+}
+
+// rotate renames the active log file, opens a fresh one and then compresses
+// and prunes the backups. The caller must hold rf.mu.
+func (rf *RotatingFile) rotate() error {
+	if rf.file != nil {
+		if err := rf.file.Close(); err != nil {
+			return fmt.Errorf("could not close log file: %w", err)
+		}
+		rf.file = nil
+	}
+
+	backup := rf.backupName(time.Now())
+	if err := os.Rename(rf.Path, backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not rename log file: %w", err)
+	}
+
+	if err := rf.open(); err != nil {
+		return err
+	}
+
+	if rf.Compress {
+		if err := compressFile(backup); err != nil {
+			return fmt.Errorf("could not compress %s: %w", backup, err)
+		}
+	}
+
+	return rf.prune()
+}
+
+// backupName returns the file name used for a backup rotated at t,
+// e.g. storage/app-2024-07-22T10-00-00.000.log.
+func (rf *RotatingFile) backupName(t time.Time) string {
+	dir := filepath.Dir(rf.Path)
+	ext := filepath.Ext(rf.Path)
+	prefix := strings.TrimSuffix(filepath.Base(rf.Path), ext)
+	return filepath.Join(dir, prefix+"-"+t.Format(rotatedTimeFormat)+ext)
+}
+
+// logBackup is a rotated log file.
+type logBackup struct {
+	path      string
+	rotatedAt time.Time // rotatedAt is when the file was rotated, from the timestamp in its name.
+}
+
+// backups returns the rotated log files, newest first.
+func (rf *RotatingFile) backups() ([]logBackup, error) {
+	dir := filepath.Dir(rf.Path)
+	ext := filepath.Ext(rf.Path)
+	prefix := strings.TrimSuffix(filepath.Base(rf.Path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []logBackup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		stamp = strings.TrimPrefix(stamp, prefix)
+		rotatedAt, err := time.ParseInLocation(rotatedTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		files = append(files, logBackup{path: filepath.Join(dir, name), rotatedAt: rotatedAt})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].rotatedAt.After(files[j].rotatedAt) })
+	return files, nil
+}
+
+// prune removes backups beyond MaxBackups and backups rotated more than MaxBackupAge ago.
+func (rf *RotatingFile) prune() error {
+	files, err := rf.backups()
+	if err != nil {
+		return fmt.Errorf("could not list log backups: %w", err)
+	}
+
+	for i, file := range files {
+		expired := rf.MaxBackupAge > 0 && time.Since(file.rotatedAt) > rf.MaxBackupAge
+		if (rf.MaxBackups > 0 && i >= rf.MaxBackups) || expired {
+			if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("could not remove log backup: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// compressFile gzips the file at path into path.gz and removes the original.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, logFileMode)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// initLogger creates a logger that writes to both stdout and a log file
+// rotated according to the given LogConfig.
+func initLogger(logFilePath string, cfg LogConfig) (*log.Logger, *RotatingFile, error) {
+	logFile, err := NewRotatingFile(
+		logFilePath,
+		int64(cfg.MaxSizeMB)*1024*1024,
+		time.Duration(cfg.MaxAgeDays)*24*time.Hour,
+		cfg.MaxBackups,
+		time.Duration(cfg.MaxBackupAgeDays)*24*time.Hour,
+		cfg.Compress,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Set up the multi-writer to write to both the log file and stdout
+	multiWriter := io.MultiWriter(os.Stdout, logFile)
+
+	// Create a new logger
+	logger := log.New(multiWriter, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
+
+	return logger, logFile, nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// backupPaths returns the base names of rf's backups, newest first.
+func backupPaths(t *testing.T, rf *RotatingFile) []string {
+	t.Helper()
+	files, err := rf.backups()
+	if err != nil {
+		t.Fatalf("backups: %s", err)
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, filepath.Base(file.path))
+	}
+	return names
+}
+
+// readFile returns the content of the file at path, failing the test if it cannot be read.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %s", err)
+	}
+	return string(data)
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	rf, err := NewRotatingFile(path, 100, 0, 0, 0, false)
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %s", err)
+	}
+	defer rf.Close()
+
+	first, second := strings.Repeat("a", 60), strings.Repeat("b", 60)
+	for _, line := range []string{first, second} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %s", err)
+		}
+	}
+
+	backups := backupPaths(t, rf)
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want one", backups)
+	}
+	if got := readFile(t, filepath.Join(filepath.Dir(path), backups[0])); got != first {
+		t.Errorf("backup = %q, want %q", got, first)
+	}
+	if got := readFile(t, path); got != second {
+		t.Errorf("log = %q, want %q", got, second)
+	}
+}
+
+func TestRotatingFileAge(t *testing.T) {
+	tests := []struct {
+		name    string
+		created time.Time // created is the recorded creation time, zero for none.
+		rotated bool
+	}{
+		{"recorded creation time past MaxAge", time.Now().Add(-48 * time.Hour), true},
+		{"recorded creation time within MaxAge", time.Now().Add(-time.Hour), false},
+		{"creation time not recorded", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			// The log was written to just now, so its modification time is recent whatever its age.
+			if err := os.WriteFile(path, []byte("old\n"), logFileMode); err != nil {
+				t.Fatalf("WriteFile: %s", err)
+			}
+			if !tt.created.IsZero() {
+				if err := os.WriteFile(path+createdSuffix, []byte(tt.created.Format(time.RFC3339Nano)), logFileMode); err != nil {
+					t.Fatalf("WriteFile: %s", err)
+				}
+			}
+
+			rf, err := NewRotatingFile(path, 0, 24*time.Hour, 0, 0, false)
+			if err != nil {
+				t.Fatalf("NewRotatingFile: %s", err)
+			}
+			defer rf.Close()
+			if _, err := rf.Write([]byte("new\n")); err != nil {
+				t.Fatalf("Write: %s", err)
+			}
+
+			rotated := len(backupPaths(t, rf)) == 1
+			if rotated != tt.rotated {
+				t.Fatalf("rotated = %t, want %t", rotated, tt.rotated)
+			}
+			if rotated && readFile(t, path) != "new\n" {
+				t.Errorf("log = %q, want only the new line", readFile(t, path))
+			}
+		})
+	}
+}
+
+func TestRotatingFileCreationTimeSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	rf, err := NewRotatingFile(path, 0, 24*time.Hour, 0, 0, false)
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %s", err)
+	}
+	defer rf.Close()
+	if _, err := rf.Write([]byte("line\n")); err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	created := rf.created
+
+	if err := rf.Reopen(); err != nil {
+		t.Fatalf("Reopen: %s", err)
+	}
+	if !rf.created.Equal(created) {
+		t.Errorf("creation time after reopening = %s, want %s", rf.created, created)
+	}
+
+	// After a rotation the new file is created now.
+	if err := rf.Rotate(); err != nil {
+		t.Fatalf("Rotate: %s", err)
+	}
+	if !rf.created.After(created) {
+		t.Errorf("creation time after rotating = %s, want after %s", rf.created, created)
+	}
+}
+
+func TestRotatingFilePrune(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name         string
+		maxBackups   int
+		maxBackupAge time.Duration
+		want         []int // want are the indexes of the existing backups kept, newest first.
+	}{
+		{"keep all", 0, 0, []int{0, 1, 2}},
+		{"max backups", 2, 0, []int{0}},
+		{"max backup age", 0, 30 * 24 * time.Hour, []int{0, 1}},
+		{"max backups and age", 3, 30 * 24 * time.Hour, []int{0, 1}},
+	}
+	existing := []time.Time{now.Add(-2 * time.Hour), now.Add(-10 * 24 * time.Hour), now.Add(-100 * 24 * time.Hour)}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "app.log")
+			// MaxAge only rotates the active file: backups older than it are kept.
+			rf, err := NewRotatingFile(path, 0, time.Hour, tt.maxBackups, tt.maxBackupAge, true)
+			if err != nil {
+				t.Fatalf("NewRotatingFile: %s", err)
+			}
+			defer rf.Close()
+
+			var names []string
+			for _, rotatedAt := range existing {
+				name := filepath.Base(rf.backupName(rotatedAt)) + ".gz"
+				if err := os.WriteFile(filepath.Join(dir, name), nil, logFileMode); err != nil {
+					t.Fatalf("WriteFile: %s", err)
+				}
+				names = append(names, name)
+			}
+			if _, err := rf.Write([]byte("line\n")); err != nil {
+				t.Fatalf("Write: %s", err)
+			}
+			if err := rf.Rotate(); err != nil {
+				t.Fatalf("Rotate: %s", err)
+			}
+
+			backups := backupPaths(t, rf)
+			if len(backups) == 0 || !strings.HasSuffix(backups[0], ".log.gz") || slices.Contains(names, backups[0]) {
+				t.Fatalf("backups = %v, want the compressed new backup first", backups)
+			}
+			var want []string
+			for _, i := range tt.want {
+				want = append(want, names[i])
+			}
+			if !slices.Equal(backups[1:], want) {
+				t.Errorf("kept backups = %v, want %v", backups[1:], want)
+			}
+		})
+	}
+}
@@ -6,17 +6,20 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"html/template"
 	"io"
+	"io/fs"
 	"log"
 	"net/http"
 	"net/smtp"
 	"net/url"
 	"os"
+	"os/signal"
 	"reflect"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -29,32 +32,34 @@ type ContactForm struct {
 
 // Project represents a project with its details.
 type Project struct {
-	Hero       string   `json:"hero"`
-	Title      string   `json:"title"`
-	Excerpt    string   `json:"excerpt"`
-	Tags       []string `json:"tags"`
-	OpenSource bool     `json:"open_source"`
-	GitRepo    string   `json:"git_repo"`
-	LiveUrl    string   `json:"live_url"`
-	CaseStudy  string   `json:"case_study"`
-	CreatedAt  string   `json:"created_at"`
-	UpdatedAt  string   `json:"updated_at"`
+	Slug       string    `json:"slug"`
+	Hero       string    `json:"hero"`
+	Gallery    []string  `json:"gallery"`
+	Title      string    `json:"title"`
+	Excerpt    string    `json:"excerpt"`
+	Tags       []string  `json:"tags"`
+	OpenSource bool      `json:"open_source"`
+	GitRepo    string    `json:"git_repo"`
+	LiveUrl    string    `json:"live_url"`
+	CaseStudy  string    `json:"case_study"`
+	CreatedAt  Timestamp `json:"created_at"`
+	UpdatedAt  Timestamp `json:"updated_at"`
 }
 
 // Post represents a blog post.
 type Post struct {
-	Locale    string `json:"locale"`
-	Slug      string `json:"slug"`
-	Title     string `json:"title"`
-	Featured  bool   `json:"featured"`
-	Excerpt   string `json:"excerpt"`
-	HeroImage string `json:"hero_image"`
-	Category  string `json:"category"`
-	Author    string `json:"author"`
-	ReadTime  string `json:"read_time"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
-	FullUrl   string `json:"full_url"`
+	Locale    string    `json:"locale"`
+	Slug      string    `json:"slug"`
+	Title     string    `json:"title"`
+	Featured  bool      `json:"featured"`
+	Excerpt   string    `json:"excerpt"`
+	HeroImage string    `json:"hero_image"`
+	Category  string    `json:"category"`
+	Author    string    `json:"author"`
+	ReadTime  Minutes   `json:"read_time"`
+	CreatedAt Timestamp `json:"created_at"`
+	UpdatedAt Timestamp `json:"updated_at"`
+	FullUrl   string    `json:"full_url"`
 }
 
 // ApiResponse represents the structure of the API response.
@@ -75,33 +80,41 @@ type Database struct {
 
 // App represents the main application struct.
 type App struct {
-	logger        *log.Logger
-	CSRFToken     CSRFToken
-	ContactToken  string
-	Database      Database
-	TemplateCache map[string]*template.Template
-	Home          Home
-	About         About
-}
-
-// Home represents the home page of the website.
-type Home struct {
-	Title            string    // Title is the title of the home page.
-	BlogUrl          string    // BlogUrl is the URL of the blog website.
-	ProjectsUrl      string    // ProjectsUrl is the URL of the projects website.
+	logger       *log.Logger
+	Config       Config
+	Assets       fs.FS
+	CSRFToken    CSRFToken
+	ContactToken string
+	data         atomic.Pointer[Database] // data is the current content snapshot, see Data.
+	Templates    *TemplateStore
+	Translations *Translations
+	PostCache    *PostCache
+	RepoStats    *RepoStatsCache
+	AboutContent AboutDocument
+	Site         SiteConfig
+	Sitemap      *Sitemap
+	Images       *ImageProxy
+	StaticAssets *AssetManifest
+}
+
+// HomePage represents the home page of the website.
+type HomePage struct {
+	Layout
 	Projects         []Project // Projects is a list of projects.
-	Posts            []Post    // Posts is a list of blog posts.
+	Featured         []Post    // Featured is a list of featured blog posts, newest first.
+	Posts            []Post    // Posts is a list of recent blog posts that are not featured, newest first.
 	Submitted        bool      // Submitted indicates whether a form has been submitted.
 	SubmittedMessage string    // SubmittedMessage is the message to display after form submission.
 	SubmittedClass   string    // SubmittedClass is the CSS class to apply after form submission.
 	CSRF             string    // CSRF is the Cross-Site Request Forgery token.
 }
 
-// About represents information about a person or organization.
-type About struct {
-	Title       string // The title of the about page.
-	BlogUrl     string // The URL of the blog website.
-	ProjectsUrl string // The URL of the projects website.
+// ErrorPage represents an error page such as the 404 page.
+type ErrorPage struct {
+	Layout
+	Status  int    // The HTTP status code of the error.
+	Heading string // The short description of the error.
+	Message string // The message explaining the error.
 }
 
 // CSRFToken represents a Cross-Site Request Forgery (CSRF) token.
@@ -133,28 +146,26 @@ func (db *Database) SaveToCache() error {
 	return nil
 }
 
-// UpdateCacheIfNewData updates the cache with new data if available.
-// It fetches new data from the API using the provided blog URL and token.
-// If new data is found, it updates the cache and returns nil.
-// If no new data is found, it logs a message and returns nil.
+// UpdateCacheIfNewData fetches the data from the API using the provided blog URL and token.
+// If it differs from db, the cache is updated and the new data is returned; otherwise db is returned.
+// db itself is never modified, so it can keep being read while the new data is fetched.
 // If there is an error while fetching new data or updating the cache, it returns an error.
-func (db *Database) UpdateCacheIfNewData(blogUrl, token string) error {
-	newData := Database{}
+func (db *Database) UpdateCacheIfNewData(blogUrl, token string) (*Database, error) {
+	newData := &Database{}
 	if err := newData.FetchFromAPI(blogUrl, token); err != nil {
-		return fmt.Errorf("could not fetch new data from API: %w", err)
+		return db, fmt.Errorf("could not fetch new data from API: %w", err)
 	}
 
-	if !reflect.DeepEqual(db, &newData) {
-		log.Println("New data found, updating cache")
-		*db = newData
-		if err := db.SaveToCache(); err != nil {
-			return fmt.Errorf("could not update cache: %w", err)
-		}
-	} else {
+	if reflect.DeepEqual(db, newData) {
 		log.Println("No new data found")
+		return db, nil
 	}
 
-	return nil
+	log.Println("New data found, updating cache")
+	if err := newData.SaveToCache(); err != nil {
+		return newData, fmt.Errorf("could not update cache: %w", err)
+	}
+	return newData, nil
 }
 
 // LoadFromCache loads data from a cache file into the Database.
@@ -171,6 +182,8 @@ func (db *Database) LoadFromCache() error {
 	if err = decoder.Decode(db); err != nil {
 		return fmt.Errorf("could not decode JSON: %w", err)
 	}
+	ensureProjectSlugs(db.Projects)
+	sortProjectsByDate(db.Projects)
 
 	return nil
 }
@@ -224,84 +237,62 @@ func (db *Database) fetchProjects() error {
 	if err != nil {
 		return fmt.Errorf("error fetching projects: %w", err)
 	}
+	ensureProjectSlugs(projects)
+	sortProjectsByDate(projects)
 	db.Projects = projects
 	return nil
 }
 
-// CacheTemplates caches the parsed templates for the given filenames.
-// It takes a variadic parameter filenames, which represents the paths of the template files.
-// If the TemplateCache is nil, it initializes it as an empty map.
-// For each filename, it parses the template file using template.ParseFiles function.
-// If there is an error parsing the template, it logs a fatal error.
-// Finally, it stores the parsed template in the TemplateCache map with the filename as the key.
-func (a *App) CacheTemplates(filenames ...string) {
-	if a.TemplateCache == nil {
-		a.TemplateCache = make(map[string]*template.Template)
-	}
+// contentRefreshInterval is how often RefreshData fetches the posts and projects again.
+const contentRefreshInterval = 5 * time.Minute
 
-	for _, filename := range filenames {
-		tmpl, err := template.ParseFiles(filename)
-		if err != nil {
-			a.logger.Fatalf("Error parsing template %s: %s\n", filename, err)
-		}
-		a.TemplateCache[filename] = tmpl
+// Data returns the current content snapshot. It is shared by every request and must not be modified:
+// FetchData replaces it as a whole instead, so a request sees either the old or the new content.
+func (a *App) Data() *Database {
+	if db := a.data.Load(); db != nil {
+		return db
 	}
+	return &Database{}
 }
 
-// FetchData fetches data from the blog API and updates the cache in the database.
+// FetchData fetches data from the blog API, updates the cache and swaps in the new content snapshot.
 // It returns an error if the data fetching or cache update fails.
 func (a *App) FetchData() error {
-	if err := a.Database.UpdateCacheIfNewData(a.GetBlogAPI(), a.GetBlogApiToken()); err != nil {
+	db, err := a.Data().UpdateCacheIfNewData(a.Config.BlogAPI, a.Config.BlogAPIToken)
+	a.data.Store(db)
+	if err != nil {
 		return fmt.Errorf("could not fetch data: %w", err)
 	}
+	a.refreshSitemap()
 	return nil
 }
 
-// EnsureData ensures that the data is loaded into the App's database.
+// RefreshData calls FetchData every interval. It runs in a single goroutine for the lifetime
+// of the server, so snapshots are only ever replaced by one writer.
+func (a *App) RefreshData(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := a.FetchData(); err != nil {
+			a.logger.Printf("Error fetching data: %s\n", err)
+		}
+	}
+}
+
+// EnsureData ensures that the data is loaded into the App's content snapshot.
 // If the data is not available in the cache, it fetches it from the API.
 // It then updates the cache if new data is available.
 func (a *App) EnsureData() error {
-	if err := a.Database.LoadFromCache(); err != nil {
+	db := &Database{}
+	if err := db.LoadFromCache(); err != nil {
 		a.logger.Printf("Error loading from cache: %s, fetching from API", err)
-		return a.Database.FetchFromAPI(a.GetBlogAPI(), a.GetBlogApiToken())
+		db = &Database{}
+		err := db.FetchFromAPI(a.Config.BlogAPI, a.Config.BlogAPIToken)
+		a.data.Store(db)
+		return err
 	}
 	a.logger.Println("Loaded data from cache")
+	a.data.Store(db)
 
-	return a.Database.UpdateCacheIfNewData(a.GetBlogAPI(), a.GetBlogApiToken())
-}
-
-// GetBlogUrl returns the URL of the blog.
-// It first checks the value of the environment variable "BLOG_URL".
-// If the environment variable is not set, it falls back to "http://localhost:8000".
-func (a *App) GetBlogUrl() string {
-	return urlFallback(
-		os.Getenv("BLOG_URL"),
-		"http://localhost:8000",
-	)
-}
-
-// GetBlogAPI returns the URL of the blog API. It first checks the value of the "BLOG_API" environment variable.
-// If the environment variable is not set, it falls back to the default URL "http://localhost:8000/api/posts".
-func (a *App) GetBlogAPI() string {
-	return urlFallback(
-		os.Getenv("BLOG_API"),
-		"http://localhost:8000/api/posts",
-	)
-}
-
-// GetBlogApiToken returns the API token for the blog.
-func (a *App) GetBlogApiToken() string {
-	return os.Getenv("BLOG_API_TOKEN")
-}
-
-// GetBlogClientId returns the client ID for the blog.
-func (a *App) GetBlogClientId() string {
-	return os.Getenv("BLOG_CLIENT_ID")
-}
-
-// GetBlogClientSecret returns the client secret for the blog.
-func (a *App) GetBlogClientSecret() string {
-	return os.Getenv("BLOG_CLIENT_SECRET")
+	return a.FetchData()
 }
 
 // GetBlogApiAuthToken retrieves the API authentication token for the blog.
@@ -314,8 +305,8 @@ func (a *App) GetBlogApiAuthToken() string {
 
 	formData := map[string]string{
 		"grant_type":    "client_credentials",
-		"client_id":     a.GetBlogClientId(),
-		"client_secret": a.GetBlogClientSecret(),
+		"client_id":     a.Config.BlogClientID,
+		"client_secret": a.Config.BlogClientSecret,
 		"scope":         "",
 	}
 	formDataBytes, err := json.Marshal(formData)
@@ -323,7 +314,7 @@ func (a *App) GetBlogApiAuthToken() string {
 		a.logger.Fatalf("Error marshalling form data: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", a.GetBlogUrl()+"/oauth/token", bytes.NewBuffer(formDataBytes))
+	req, err := http.NewRequest("POST", a.Config.BlogURL+"/oauth/token", bytes.NewBuffer(formDataBytes))
 	if err != nil {
 		a.logger.Fatalf("Error creating request: %v", err)
 	}
@@ -358,26 +349,6 @@ func (a *App) GetBlogApiAuthToken() string {
 	return accessToken
 }
 
-// GetProjectsUrl returns the URL for retrieving projects.
-// It first checks the value of the PROJECTS_URL environment variable.
-// If the environment variable is not set, it falls back to "http://localhost:8000/projects".
-func (a *App) GetProjectsUrl() string {
-	return urlFallback(
-		os.Getenv("PROJECTS_URL"),
-		"http://localhost:8000/projects",
-	)
-}
-
-// GetProjectsAPI returns the URL of the projects API.
-// It first checks the value of the PROJECTS_API environment variable.
-// If the environment variable is not set, it falls back to the default URL "http://localhost:8000/api/projects".
-func (a *App) GetProjectsAPI() string {
-	return urlFallback(
-		os.Getenv("PROJECTS_API"),
-		"http://localhost:8000/api/projects",
-	)
-}
-
 // GetCSRFToken returns the CSRF token for the App.
 // If the CSRF token is empty, it generates a new one using the generateCSRFToken function.
 func (a *App) GetCSRFToken() string {
@@ -413,63 +384,41 @@ func (a *App) ValidateContactToken(token string) bool {
 }
 
 // HomeHandler handles the HTTP request for the home page.
-// It sets the CSRF token, fetches data, and populates the home page with projects and recent posts.
+// It sets the CSRF token and populates the home page with projects and recent posts from the content snapshot.
 // It also checks for query parameters related to form submission status and updates the home page accordingly.
-// If the template is not found or there is an error rendering the template, it returns an HTTP error.
+// If the template is not found or there is an error rendering the template, it renders the 500 error page.
 func (a *App) HomeHandler(w http.ResponseWriter, r *http.Request) {
-	a.Home.CSRF = a.NewCSRFToken()
-	if err := a.FetchData(); err != nil {
-		a.logger.Printf("Error fetching data: %s\n", err)
-	}
-	a.Home.Projects = a.Database.Projects
-	a.Home.Posts = a.Database.Posts.Recent
-	a.Home.SubmittedClass = "hidden"
+	db := a.Data()
+	page := HomePage{
+		Layout:         a.layout(r, a.T(r, "title.home")),
+		Projects:       db.Projects,
+		SubmittedClass: "hidden",
+		CSRF:           a.NewCSRFToken(),
+	}
+	page.SEO.StructuredData = []any{a.personSchema(r, page.Layout)}
+	page.Featured, page.Posts = db.HomePosts(page.Locale, a.Config.Home.FeaturedPosts, a.Config.Home.RecentPosts)
 	// Check for query parameters
 	query := r.URL.Query()
 
 	if a.ValidateContactToken(query.Get("token")) && query.Get("status") == "success" {
-		a.Home.Submitted = true
-		a.Home.SubmittedClass = "border-green-500"
-		a.Home.SubmittedMessage = urlFallback(query.Get("message"), "Contact form submitted successfully")
+		page.Submitted = true
+		page.SubmittedClass = "border-green-500"
+		page.SubmittedMessage = orDefault(query.Get("message"), "Contact form submitted successfully")
 	}
 
 	if a.ValidateContactToken(query.Get("token")) && query.Get("status") == "error" {
-		a.Home.Submitted = true
-		a.Home.SubmittedClass = "border-red-500"
-		a.Home.SubmittedMessage = urlFallback(query.Get("message"), "An error occurred while submitting the contact form")
+		page.Submitted = true
+		page.SubmittedClass = "border-red-500"
+		page.SubmittedMessage = orDefault(query.Get("message"), "An error occurred while submitting the contact form")
 	}
 
 	a.ContactToken = ""
 
-	if a.Home.Submitted {
-		a.logger.Printf("Contact form submitted: %s\n", a.Home.SubmittedMessage)
-	}
-
-	tmpl, ok := a.TemplateCache["templates/index.html"]
-	if !ok {
-		http.Error(w, "Unable to load template", http.StatusInternalServerError)
-		return
-	}
-	if err := tmpl.Execute(w, a.Home); err != nil {
-		http.Error(w, "Unable to render template", http.StatusInternalServerError)
+	if page.Submitted {
+		a.logger.Printf("Contact form submitted: %s\n", page.SubmittedMessage)
 	}
-}
 
-// AboutHandler handles the HTTP request for the about page.
-// It loads the "templates/about.html" template from the App's TemplateCache
-// and renders it with the data stored in the App's About field.
-// If the template or rendering fails, it returns an HTTP 500 Internal Server Error.
-func (a *App) AboutHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl, ok := a.TemplateCache["templates/about.html"]
-	if !ok {
-		a.logger.Printf("Error loading about template from cache")
-		http.Error(w, "Unable to load template", http.StatusInternalServerError)
-		return
-	}
-	if err := tmpl.Execute(w, a.About); err != nil {
-		a.logger.Printf("Error rendering about template: %s\n", err)
-		http.Error(w, "Unable to render template", http.StatusInternalServerError)
-	}
+	a.Render(w, r, http.StatusOK, "templates/index.html", page)
 }
 
 // ContactFormHandler handles the HTTP request for the contact form.
@@ -554,7 +503,7 @@ func (a *App) ContactFormJSONHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Received contact form submission: %+v\n", form)
 
 	// Send the email
-	if err := sendEmail(form); err != nil {
+	if err := sendEmail(a.Config.SMTP, form); err != nil {
 		response.Status = "error"
 		response.Message = "Error sending email"
 		w.WriteHeader(http.StatusInternalServerError)
@@ -575,7 +524,7 @@ func (a *App) ContactFormJSONHandler(w http.ResponseWriter, r *http.Request) {
 // If the CSRF token is invalid, it redirects to the contact form page with an error status.
 // If the form data is valid, it processes the form data and redirects to the contact form page with a success status.
 func (a *App) ContactFormRedirectHandler(w http.ResponseWriter, r *http.Request) {
-	redirectURL, _ := url.Parse("/#contactForm")
+	redirectURL, _ := url.Parse(Layout{Prefix: a.localePrefix(r)}.Link("/") + "#contactForm")
 	query := redirectURL.Query()
 	query.Set("status", "error")
 
@@ -618,7 +567,7 @@ func (a *App) ContactFormRedirectHandler(w http.ResponseWriter, r *http.Request)
 	log.Printf("Received contact form submission: %+v\n", form)
 
 	// Send the email
-	if err := sendEmail(form); err != nil {
+	if err := sendEmail(a.Config.SMTP, form); err != nil {
 		a.logger.Printf("Error sending email: %s\n", err)
 		query.Set("message", "Error sending email")
 		redirectURL.RawQuery = query.Encode()
@@ -634,86 +583,140 @@ func (a *App) ContactFormRedirectHandler(w http.ResponseWriter, r *http.Request)
 	http.Redirect(w, r, redirectURL.String(), http.StatusSeeOther)
 }
 
-func (a *App) NotFoundHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusNotFound)
-	tmpl, ok := a.TemplateCache["templates/404.html"]
-	if !ok {
-		a.logger.Printf("Error loading 404 template from cache")
-		http.Error(w, "Unable to load template", http.StatusInternalServerError)
-		return
+// main is the entry point of the application.
+// It loads and validates the configuration, runs a command if one was given,
+// initializes the `app` variable, loads the translations, site configuration and about content,
+// hashes the static assets, caches the templates, ensures data is loaded from the API,
+// sets up the HTTP request handlers, builds the sitemap and starts the server.
+func main() {
+	cfg, args, err := LoadConfig(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Error loading configuration: %s\n", err)
 	}
-	if err := tmpl.Execute(w, nil); err != nil {
-		a.logger.Printf("Error rendering 404 template: %s\n", err)
-		http.Error(w, "Unable to render template", http.StatusInternalServerError)
+
+	if len(args) > 0 {
+		os.Exit(runCommand(cfg, args))
 	}
-}
 
-// main is the entry point of the application.
-// It initializes the `app` variable, caches the templates,
-// sets the port, ensures data is loaded from the API,
-// initializes the `Home` and `About` structs,
-// sets up the HTTP request handlers, and starts the server.
-func main() {
-	logger, err := initLogger("storage/app.log")
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%s\n", err)
+	}
+
+	logger, logFile, err := initLogger("storage/app.log", cfg.Log)
 	if err != nil {
 		log.Fatalf("Error initializing logger: %s\n", err)
 	}
+	defer logFile.Close()
+
+	// Reopen the log file on SIGHUP so external logrotate can move it away
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			if err := logFile.Reopen(); err != nil {
+				log.Printf("Error reopening log file: %s\n", err)
+				continue
+			}
+			logger.Println("Reopened log file")
+		}
+	}()
 
 	app := App{
 		logger: logger,
+		Config: cfg,
+		Assets: assetsFS(cfg.AssetsDir),
 	}
 
-	app.CacheTemplates("templates/index.html", "templates/about.html", "templates/404.html")
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "5050"
+	if cfg.AssetsDir != "" {
+		app.logger.Printf("Serving templates and static assets from %s\n", cfg.AssetsDir)
 	}
 
-	if err := app.EnsureData(); err != nil {
-		app.logger.Printf("Error loading from API: %s", err.Error())
+	if !cfg.SMTP.Configured() {
+		app.logger.Println("SMTP is not configured, contact form emails will not be sent")
 	}
 
-	app.Home = Home{
-		Title:            "Welcome To My Portfolio | Swaye Chateau",
-		BlogUrl:          app.GetBlogUrl(),
-		ProjectsUrl:      app.GetProjectsUrl(),
-		Projects:         []Project{},
-		Posts:            []Post{},
-		Submitted:        false,
-		SubmittedMessage: "",
-		SubmittedClass:   "hidden",
+	translations, err := LoadTranslations(app.Assets, "locales", cfg.Locales)
+	if err != nil {
+		app.logger.Fatalf("Error loading translations: %s\n", err)
 	}
+	app.Translations = translations
 
-	app.About = About{
-		Title:       "About Me | Swaye Chateau",
-		BlogUrl:     app.GetBlogUrl(),
-		ProjectsUrl: app.GetProjectsUrl(),
+	site, err := LoadSiteConfig(app.Assets, cfg.SiteConfig, cfg.Locales)
+	if err != nil {
+		app.logger.Fatalf("Error loading site configuration: %s\n", err)
 	}
+	app.Site = site
 
-	mux := http.NewServeMux()
-	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+	if err := app.LoadAboutContent(); err != nil {
+		app.logger.Fatalf("Error loading about content: %s\n", err)
+	}
 
-	mux.HandleFunc("/", app.HomeHandler)
-	mux.HandleFunc("/about", app.AboutHandler)
-	mux.HandleFunc("/contact", app.ContactFormHandler)
+	static, err := fs.Sub(app.Assets, "static")
+	if err != nil {
+		app.logger.Fatalf("Error opening static assets: %s\n", err)
+	}
+	app.CacheStaticAssets(static)
+
+	app.CacheTemplates(
+		"templates/index.html",
+		"templates/about.html",
+		"templates/blog.html",
+		"templates/post.html",
+		"templates/projects.html",
+		"templates/project.html",
+		"templates/taxonomy.html",
+		"templates/error.html",
+	)
 
-	// Set custom 404 handler
-	mux.HandleFunc("/404", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/" && r.URL.Path != "/about" && r.URL.Path != "/contact" && !strings.HasPrefix(r.URL.Path, "/static/") {
-			app.NotFoundHandler(w, r)
-		} else {
-			mux.ServeHTTP(w, r)
-		}
+	app.PostCache = NewPostCache(10*time.Minute, func(locale, slug string) (PostBody, error) {
+		return fetchPostBodyFromAPI(cfg.BlogAPI, cfg.BlogAPIToken, locale, slug)
 	})
+	app.RepoStats = NewRepoStatsCache(time.Hour)
+
+	app.Images = NewImageProxy(static, cfg.Images, "storage/img")
 
-	loggedMux := loggingMiddleware(app.logger, mux)
+	if err := app.EnsureData(); err != nil {
+		app.logger.Printf("Error loading from API: %s", err.Error())
+	}
+
+	router, err := app.Routes()
+	if err != nil {
+		app.logger.Fatalf("Error building routes: %s\n", err)
+	}
+	app.Sitemap = NewSitemap(router.Routes(), cfg.Locales, cfg.Sitemap.MaxURLs)
+	app.refreshSitemap()
+	go app.RefreshData(contentRefreshInterval)
+
+	loggedMux := loggingMiddleware(app.logger, compressionMiddleware(cfg.Compression, app.securityMiddleware(app.hostMiddleware(app.recoverMiddleware(app.localeMiddleware(router))))))
 
-	app.logger.Println("Starting server on :" + port)
-	if err := http.ListenAndServe(":"+port, loggedMux); err != nil {
+	if cfg.TLS.Mode != "" {
+		err = app.ServeTLS(loggedMux)
+	} else {
+		app.logger.Println("Starting server on :" + cfg.Port)
+		err = newServer(":"+cfg.Port, loggedMux).ListenAndServe()
+	}
+	if err != nil {
 		app.logger.Fatalf("Could not start server: %s\n", err.Error())
 	}
 }
 
+// runCommand runs the command given on the command line and returns the process exit code.
+// The only supported command is "config print", which prints the redacted configuration
+// followed by any validation errors.
+func runCommand(cfg Config, args []string) int {
+	if len(args) == 2 && args[0] == "config" && args[1] == "print" {
+		cfg.Print(os.Stdout)
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "\nInvalid configuration:\n%s\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q, usage: portfolio [flags] [config print]\n", strings.Join(args, " "))
+	return 2
+}
+
 // fetchPostsFromAPI fetches posts from the specified API endpoint.
 // It sends a GET request to the provided URL with the given token as authorization.
 // The function returns an ApiResponse and an error if any occurred.
@@ -757,6 +760,7 @@ func fetchPostsFromAPI(url, token string) (ApiResponse, error) {
 func fetchProjectsFromAPI() ([]Project, error) {
 	return []Project{
 		{
+			Slug:       "hulu-clone",
 			Hero:       "/static/img/project-hulu-clone.png",
 			Title:      "Hulu Clone",
 			Excerpt:    "A read-only clone of Hulu's website.",
@@ -767,6 +771,7 @@ func fetchProjectsFromAPI() ([]Project, error) {
 			CaseStudy:  "https://nobodycare.dev/en/post/building-a-hulu-clone",
 		},
 		{
+			Slug:       "file-server",
 			Hero:       "https://file.swayechateau.com/view/globaliyndTnSCK14onpASVq7n5?share_code=s5LUL0lAdDLS",
 			Title:      "File Server",
 			Excerpt:    "Custom built CDN for my media files.",
@@ -777,6 +782,7 @@ func fetchProjectsFromAPI() ([]Project, error) {
 			CaseStudy:  "https://nobodycare.dev/en/post/building-a-file-server-api",
 		},
 		{
+			Slug:       "web-meta-grabber",
 			Hero:       "https://file.swayechateau.com/view/globalMaJKf2UDzFdqba7hG96U6?share_code=s6LHjQlIsFHc",
 			Title:      "Web Meta Grabber",
 			Excerpt:    "I Wanted an api I had permissions to use to get the meta data from websites for a chat application I was building.",
@@ -818,16 +824,14 @@ func validateInput(name, email, message string) error {
 	return nil
 }
 
-// sendEmail sends an email using the provided contact form data.
-// It takes a ContactForm struct as input and returns an error if any occurred during the email sending process.
-func sendEmail(form ContactForm) error {
-	from := os.Getenv("EMAIL_FROM")
-	password := os.Getenv("EMAIL_PASSWORD")
-	to := os.Getenv("EMAIL_TO")
-	smtpHost := os.Getenv("SMTP_HOST")
-	smtpPort := os.Getenv("SMTP_PORT")
+// sendEmail sends an email using the provided SMTP settings and contact form data.
+// It returns an error if SMTP is not configured or if any occurred during the email sending process.
+func sendEmail(cfg SMTPConfig, form ContactForm) error {
+	if !cfg.Configured() {
+		return fmt.Errorf("SMTP is not configured")
+	}
 
-	auth := smtp.PlainAuth("", from, password, smtpHost)
+	auth := smtp.PlainAuth("", cfg.From, cfg.Password, cfg.Host)
 
 	message := []byte(fmt.Sprintf(
 		"To: %s\r\n"+
@@ -837,9 +841,9 @@ func sendEmail(form ContactForm) error {
 			"Name: %s\n"+
 			"Email: %s\n"+
 			"Message: %s",
-		to, form.Name, form.Email, form.Message))
+		cfg.To, form.Name, form.Email, form.Message))
 
-	return smtp.SendMail(smtpHost+":"+smtpPort, auth, "noreply@swaye.dev", []string{to}, message)
+	return smtp.SendMail(cfg.Host+":"+cfg.Port, auth, "noreply@swaye.dev", []string{cfg.To}, message)
 }
 
 // generateCSRFToken generates a CSRF token.
@@ -859,28 +863,26 @@ func generateCSRFToken() (CSRFToken, error) {
 	}, nil
 }
 
-// urlFallback returns the given URL if it is not empty, otherwise it returns the fallback URL.
-func urlFallback(url, fallback string) string {
-	if url == "" {
+// orDefault returns value if it is not empty, otherwise it returns fallback.
+func orDefault(value, fallback string) string {
+	if value == "" {
 		return fallback
 	}
-	return url
+	return value
 }
 
-func initLogger(logFilePath string) (*log.Logger, error) {
-	// Open the log file in append mode, create it if it doesn't exist
-	logFile, err := os.OpenFile(logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
-	if err != nil {
-		return nil, err
+// newServer creates an HTTP server for handler on addr, with timeouts so slow or idle clients
+// cannot hold connections open indefinitely when the server is reachable without a reverse proxy.
+// The write timeout leaves room for the image proxy to fetch and resize an image.
+func newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
 	}
-
-	// Set up the multi-writer to write to both the log file and stdout
-	multiWriter := io.MultiWriter(os.Stdout, logFile)
-
-	// Create a new logger
-	logger := log.New(multiWriter, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
-
-	return logger, nil
 }
 
 func loggingMiddleware(logger *log.Logger, next http.Handler) http.Handler {
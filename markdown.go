@@ -0,0 +1,236 @@
+package main
+
+import (
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	headingRegex     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	ruleRegex        = regexp.MustCompile(`^(\*\s*){3,}$|^(-\s*){3,}$|^(_\s*){3,}$`)
+	unorderedRegex   = regexp.MustCompile(`^\s{0,3}[-*+]\s+(.*)$`)
+	orderedRegex     = regexp.MustCompile(`^\s{0,3}\d+[.)]\s+(.*)$`)
+	fenceRegex       = regexp.MustCompile("^(```|~~~)\\s*([\\w+-]*)")
+	blockquoteRegex  = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+	markdownEscapes  = "\\`*_{}[]()#+-.!>"
+	allowedURLScheme = map[string]bool{"": true, "http": true, "https": true, "mailto": true}
+)
+
+// renderMarkdown converts a Markdown document to HTML.
+// It supports the subset used by the blog: headings, paragraphs, fenced code blocks,
+// block quotes, ordered and unordered lists, horizontal rules, and inline code,
+// emphasis, links and images. Raw HTML is escaped and unsafe link schemes are dropped,
+// so the result is safe to render without further sanitizing.
+func renderMarkdown(source string) template.HTML {
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+	var b strings.Builder
+	renderBlocks(&b, lines)
+	return template.HTML(b.String())
+}
+
+// renderBlocks renders block-level Markdown from lines into b.
+func renderBlocks(b *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case fenceRegex.MatchString(trimmed):
+			match := fenceRegex.FindStringSubmatch(trimmed)
+			fence, lang := match[1], match[2]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			i++ // skip the closing fence
+			if lang != "" {
+				b.WriteString(`<pre><code class="language-` + html.EscapeString(lang) + `">`)
+			} else {
+				b.WriteString("<pre><code>")
+			}
+			b.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			b.WriteString("</code></pre>\n")
+
+		case headingRegex.MatchString(trimmed):
+			match := headingRegex.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(match[1])))
+			b.WriteString("<h" + level + ">" + renderInline(match[2]) + "</h" + level + ">\n")
+			i++
+
+		case ruleRegex.MatchString(trimmed):
+			b.WriteString("<hr>\n")
+			i++
+
+		case blockquoteRegex.MatchString(line):
+			var quoted []string
+			for ; i < len(lines) && blockquoteRegex.MatchString(lines[i]); i++ {
+				quoted = append(quoted, blockquoteRegex.FindStringSubmatch(lines[i])[1])
+			}
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quoted)
+			b.WriteString("</blockquote>\n")
+
+		case unorderedRegex.MatchString(line), orderedRegex.MatchString(line):
+			itemRegex, tag := unorderedRegex, "ul"
+			if orderedRegex.MatchString(line) {
+				itemRegex, tag = orderedRegex, "ol"
+			}
+			b.WriteString("<" + tag + ">\n")
+			for i < len(lines) && itemRegex.MatchString(lines[i]) {
+				item := itemRegex.FindStringSubmatch(lines[i])[1]
+				// Indented lines continue the current item.
+				for i++; i < len(lines) && strings.HasPrefix(lines[i], "  ") && strings.TrimSpace(lines[i]) != "" &&
+					!itemRegex.MatchString(lines[i]); i++ {
+					item += " " + strings.TrimSpace(lines[i])
+				}
+				b.WriteString("<li>" + renderInline(item) + "</li>\n")
+			}
+			b.WriteString("</" + tag + ">\n")
+
+		default:
+			var paragraph []string
+			for ; i < len(lines) && isParagraphLine(lines[i]); i++ {
+				text := strings.TrimSpace(lines[i])
+				if strings.HasSuffix(lines[i], "  ") {
+					text += "\x00" // hard line break, replaced after inline rendering
+				}
+				paragraph = append(paragraph, text)
+			}
+			rendered := renderInline(strings.Join(paragraph, "\n"))
+			b.WriteString("<p>" + strings.ReplaceAll(rendered, "\x00", "<br>") + "</p>\n")
+		}
+	}
+}
+
+// isParagraphLine reports whether line continues a paragraph rather than starting a new block.
+func isParagraphLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" &&
+		!fenceRegex.MatchString(trimmed) &&
+		!headingRegex.MatchString(trimmed) &&
+		!ruleRegex.MatchString(trimmed) &&
+		!blockquoteRegex.MatchString(line) &&
+		!unorderedRegex.MatchString(line) &&
+		!orderedRegex.MatchString(line)
+}
+
+// renderInline converts inline Markdown to HTML, escaping everything else.
+func renderInline(text string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && strings.IndexByte(markdownEscapes, text[i+1]) >= 0:
+			b.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+
+		case c == '`':
+			if end := strings.IndexByte(text[i+1:], '`'); end >= 0 {
+				b.WriteString("<code>" + html.EscapeString(text[i+1:i+1+end]) + "</code>")
+				i += end + 2
+				continue
+			}
+			b.WriteString("`")
+			i++
+
+		case c == '!' && i+1 < len(text) && text[i+1] == '[':
+			if label, dest, n, ok := parseLink(text[i+1:]); ok {
+				b.WriteString(`<img src="` + html.EscapeString(safeURL(dest)) + `" alt="` + html.EscapeString(label) + `" loading="lazy">`)
+				i += n + 1
+				continue
+			}
+			b.WriteString("!")
+			i++
+
+		case c == '[':
+			if label, dest, n, ok := parseLink(text[i:]); ok {
+				href := safeURL(dest)
+				attrs := ""
+				if strings.HasPrefix(href, "http") {
+					attrs = ` target="_blank" rel="noopener noreferrer"`
+				}
+				b.WriteString(`<a href="` + html.EscapeString(href) + `"` + attrs + `>` + renderInline(label) + `</a>`)
+				i += n
+				continue
+			}
+			b.WriteString("[")
+			i++
+
+		case c == '*' || (c == '_' && (i == 0 || !isWordByte(text[i-1]))):
+			delim := string(c)
+			if strings.HasPrefix(text[i:], delim+delim) {
+				delim += delim
+			}
+			rest := text[i+len(delim):]
+			if end := strings.Index(rest, delim); end > 0 && !strings.HasPrefix(rest, " ") {
+				tag := "em"
+				if len(delim) == 2 {
+					tag = "strong"
+				}
+				b.WriteString("<" + tag + ">" + renderInline(rest[:end]) + "</" + tag + ">")
+				i += len(delim)*2 + end
+				continue
+			}
+			b.WriteString(delim)
+			i += len(delim)
+
+		default:
+			b.WriteString(html.EscapeString(text[i : i+1]))
+			i++
+		}
+	}
+
+	return b.String()
+}
+
+// isWordByte reports whether c is an ASCII letter or digit, so snake_case words are not emphasized.
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// parseLink parses "[label](destination)" at the start of text and returns the label,
+// the destination and the number of bytes consumed.
+func parseLink(text string) (label, dest string, n int, ok bool) {
+	closeLabel := strings.Index(text, "](")
+	if !strings.HasPrefix(text, "[") || closeLabel < 0 {
+		return "", "", 0, false
+	}
+	// Find the closing parenthesis, allowing balanced parentheses inside the destination.
+	closeDest, depth := -1, 0
+	for i, c := range text[closeLabel+2:] {
+		if c == '(' {
+			depth++
+		} else if c == ')' {
+			if depth == 0 {
+				closeDest = i
+				break
+			}
+			depth--
+		}
+	}
+	if closeDest < 0 {
+		return "", "", 0, false
+	}
+	dest = strings.TrimSpace(text[closeLabel+2 : closeLabel+2+closeDest])
+	// Drop an optional title: [label](url "title")
+	if space := strings.IndexAny(dest, " \t"); space >= 0 {
+		dest = dest[:space]
+	}
+	return text[1:closeLabel], dest, closeLabel + 3 + closeDest, true
+}
+
+// safeURL returns raw if it uses an allowed scheme, otherwise "#".
+func safeURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || !allowedURLScheme[strings.ToLower(u.Scheme)] {
+		return "#"
+	}
+	return raw
+}
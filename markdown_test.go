@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"paragraph", "Hello\nworld", "<p>Hello\nworld</p>\n"},
+		{"hard line break", "Hello  \nworld", "<p>Hello<br>\nworld</p>\n"},
+		{"heading", "## Title ##", "<h2>Title</h2>\n"},
+		{"rule", "***", "<hr>\n"},
+		{"fenced code", "```go\nif a < b {}\n```", "<pre><code class=\"language-go\">if a &lt; b {}</code></pre>\n"},
+		{"block quote", "> quoted\n> text", "<blockquote>\n<p>quoted\ntext</p>\n</blockquote>\n"},
+		{"unordered list", "- one\n- two\n  continued", "<ul>\n<li>one</li>\n<li>two continued</li>\n</ul>\n"},
+		{"ordered list", "1. one\n2) two", "<ol>\n<li>one</li>\n<li>two</li>\n</ol>\n"},
+		{"emphasis", "*em* **strong** __strong__ _em_", "<p><em>em</em> <strong>strong</strong> <strong>strong</strong> <em>em</em></p>\n"},
+		{"snake case", "snake_case_name", "<p>snake_case_name</p>\n"},
+		{"inline code", "run `a < b`", "<p>run <code>a &lt; b</code></p>\n"},
+		{"escape", `\*not em\*`, "<p>*not em*</p>\n"},
+		{"relative link", "[about](/about)", "<p><a href=\"/about\">about</a></p>\n"},
+		{"external link", "[Go](https://go.dev \"Go\")", "<p><a href=\"https://go.dev\" target=\"_blank\" rel=\"noopener noreferrer\">Go</a></p>\n"},
+		{"link with parentheses", "[wiki](https://en.wikipedia.org/wiki/Go_(language))", "<p><a href=\"https://en.wikipedia.org/wiki/Go_(language)\" target=\"_blank\" rel=\"noopener noreferrer\">wiki</a></p>\n"},
+		{"image", "![a cat](/img/cat.png)", "<p><img src=\"/img/cat.png\" alt=\"a cat\" loading=\"lazy\"></p>\n"},
+		{"raw HTML is escaped", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"unsafe scheme is dropped", "[click](javascript:alert(1))", "<p><a href=\"#\">click</a></p>\n"},
+		{"unsafe image scheme is dropped", "![x](data:text/html,hi)", "<p><img src=\"#\" alt=\"x\" loading=\"lazy\"></p>\n"},
+		{"attribute injection", `[x](/a"onmouseover="alert(1))`, "<p><a href=\"/a&#34;onmouseover=&#34;alert(1)\">x</a></p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(renderMarkdown(tt.source)); got != tt.want {
+				t.Errorf("renderMarkdown(%q)\n got %q\nwant %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Dimensions of the OpenGraph images, the size recommended by Facebook, Twitter and Mastodon.
+const (
+	ogImageWidth  = 1200
+	ogImageHeight = 630
+)
+
+// ogImageDir is the directory rendered OpenGraph images are cached in.
+const ogImageDir = "storage/og"
+
+// ogImageVersion is part of every cache key, so changing the card design invalidates the cached images.
+const ogImageVersion = "1"
+
+// Colours of the OpenGraph images, matching the site's dark theme.
+var (
+	ogBackground = color.RGBA{0x11, 0x11, 0x11, 0xff}
+	ogAccent     = color.RGBA{0x4a, 0xde, 0x80, 0xff}
+	ogText       = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	ogMuted      = color.RGBA{0x9c, 0xa3, 0xaf, 0xff}
+)
+
+// OGCard is the content drawn on an OpenGraph image.
+type OGCard struct {
+	Label string // Label names the kind of page, e.g. "PROJECT".
+	Title string // Title is the title of the project or post.
+	Meta  string // Meta is the tags or category shown under the title.
+	Site  string // Site is the site title shown at the bottom of the card.
+}
+
+// key returns the cache key of the card, which changes whenever its content does.
+func (c OGCard) key() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{ogImageVersion, c.Label, c.Title, c.Meta, c.Site}, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Drawable reports whether the bitmap font covers all the text of the card. Cards in other scripts,
+// such as Chinese titles, would be drawn as rows of question marks, so pages use their hero image
+// or the site image instead.
+func (c OGCard) Drawable() bool {
+	return fontCovers(c.Label + c.Title + c.Meta + c.Site)
+}
+
+// Render draws the card as a 1200x630 image: the label in the accent colour, the title wrapped over
+// up to three lines, the tags or category and the site title, next to an accent bar.
+// Characters the bitmap font does not cover are drawn as question marks; see Drawable.
+func (c OGCard) Render() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, ogImageWidth, ogImageHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(ogBackground), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, 24, ogImageHeight), image.NewUniform(ogAccent), image.Point{}, draw.Src)
+
+	const margin = 96
+	width := ogImageWidth - 2*margin
+
+	drawText(img, c.Label, margin, 80, 4, ogAccent)
+
+	y := 160
+	for _, line := range wrapText(c.Title, width/(glyphAdvance*8), 3) {
+		drawText(img, line, margin, y, 8, ogText)
+		y += (glyphHeight + 4) * 8
+	}
+
+	if c.Meta != "" {
+		meta := wrapText(c.Meta, width/(glyphAdvance*4), 1)
+		drawText(img, meta[0], margin, y+24, 4, ogMuted)
+	}
+
+	draw.Draw(img, image.Rect(margin, ogImageHeight-120, ogImageWidth-margin, ogImageHeight-116), image.NewUniform(ogMuted), image.Point{}, draw.Src)
+	drawText(img, c.Site, margin, ogImageHeight-88, 5, ogText)
+
+	return img
+}
+
+// drawText draws s with its top-left corner at x, y, each font pixel scaled to a scale x scale square.
+func drawText(img draw.Image, s string, x, y, scale int, c color.Color) {
+	src := image.NewUniform(c)
+	for _, r := range s {
+		glyph, ok := bitmapFont[r]
+		if !ok {
+			glyph = bitmapFont['?']
+		}
+		for row, bits := range glyph {
+			for col := 0; col < glyphWidth; col++ {
+				if bits&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				px, py := x+col*scale, y+row*scale
+				draw.Draw(img, image.Rect(px, py, px+scale, py+scale), src, image.Point{}, draw.Src)
+			}
+		}
+		x += glyphAdvance * scale
+	}
+}
+
+// wrapText splits s into at most maxLines lines of at most width characters, breaking between words.
+// Text that does not fit is cut with an ellipsis.
+func wrapText(s string, width, maxLines int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		for len([]rune(word)) > width {
+			if line != "" {
+				lines, line = append(lines, line), ""
+			}
+			lines = append(lines, string([]rune(word)[:width]))
+			word = string([]rune(word)[width:])
+		}
+		switch {
+		case line == "":
+			line = word
+		case len([]rune(line))+1+len([]rune(word)) <= width:
+			line += " " + word
+		default:
+			lines, line = append(lines, line), word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		last := []rune(lines[maxLines-1])
+		if len(last)+3 > width {
+			last = last[:width-3]
+		}
+		lines[maxLines-1] = strings.TrimSpace(string(last)) + "..."
+	}
+	return lines
+}
+
+// ogImagePath returns the URL path of the OpenGraph image of a project, or of a post if locale is set.
+func ogImagePath(kind, locale, slug string) string {
+	if locale != "" {
+		return "/og/" + kind + "/" + locale + "/" + slug + ".png"
+	}
+	return "/og/" + kind + "/" + slug + ".png"
+}
+
+// projectCard returns the OpenGraph card of project.
+func (a *App) projectCard(project Project) OGCard {
+	card := OGCard{Label: "PROJECT", Title: project.Title, Site: a.Site.Title}
+	if len(project.Tags) > 0 {
+		card.Meta = "#" + strings.Join(project.Tags, " #")
+	}
+	return card
+}
+
+// postCard returns the OpenGraph card of post.
+func (a *App) postCard(post Post) OGCard {
+	return OGCard{Label: "BLOG POST", Title: post.Title, Meta: post.Category, Site: a.Site.Title}
+}
+
+// OGImageHandler handles the HTTP request for the OpenGraph image of a project or post,
+// /og/project/{slug}.png or /og/post/{locale}/{slug}.png.
+// Images are rendered on first request and cached in storage/og, keyed by their content.
+// Cards the bitmap font cannot draw redirect to the hero image, or the site image if there is none.
+func (a *App) OGImageHandler(w http.ResponseWriter, r *http.Request) {
+	slug, ok := strings.CutSuffix(r.PathValue("slug"), ".png")
+	if !ok {
+		a.NotFoundHandler(w, r)
+		return
+	}
+
+	var card OGCard
+	var hero string
+	switch kind, locale := r.PathValue("kind"), r.PathValue("locale"); {
+	case kind == "project" && locale == "":
+		project, ok := a.Data().FindProject(slug)
+		if !ok {
+			a.NotFoundHandler(w, r)
+			return
+		}
+		card, hero = a.projectCard(project), project.Hero
+	case kind == "post" && locale != "":
+		post, ok := a.Data().FindPost(locale, slug)
+		if !ok {
+			a.NotFoundHandler(w, r)
+			return
+		}
+		card, hero = a.postCard(post), post.HeroImage
+	default:
+		a.NotFoundHandler(w, r)
+		return
+	}
+
+	if !card.Drawable() {
+		http.Redirect(w, r, a.absoluteImage(r, orDefault(hero, a.Site.Image)), http.StatusFound)
+		return
+	}
+
+	name, err := cachedOGImage(card)
+	if err != nil {
+		a.logger.Printf("Error rendering OpenGraph image: %s\n", err)
+		a.RenderError(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("ETag", `"`+card.key()+`"`)
+	w.Header().Set("Content-Type", "image/png")
+	http.ServeFile(w, r, name)
+}
+
+// cachedOGImage returns the path of the rendered card in ogImageDir, rendering and saving it first
+// if it is not cached. The file is written under a temporary name and renamed into place,
+// so concurrent requests never serve a partially written image.
+func cachedOGImage(card OGCard) (string, error) {
+	name := filepath.Join(ogImageDir, card.key()+".png")
+	if _, err := os.Stat(name); err == nil {
+		return name, nil
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, card.Render()); err != nil {
+		return "", fmt.Errorf("error encoding image: %w", err)
+	}
+
+	if err := os.MkdirAll(ogImageDir, 0o755); err != nil {
+		return "", fmt.Errorf("error creating cache directory: %w", err)
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", name, time.Now().UnixNano())
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return "", fmt.Errorf("error writing image: %w", err)
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("error saving image: %w", err)
+	}
+	return name, nil
+}
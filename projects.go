@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// slugInvalidChars matches runs of characters that are not allowed in a slug.
+var slugInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// RepoStats represents the statistics of a project's source repository.
+type RepoStats struct {
+	Stars      int    `json:"stargazers_count"`  // Stars is the number of stargazers.
+	Forks      int    `json:"forks_count"`       // Forks is the number of forks.
+	OpenIssues int    `json:"open_issues_count"` // OpenIssues is the number of open issues.
+	Language   string `json:"language"`          // Language is the primary language of the repository.
+	PushedAt   string `json:"pushed_at"`         // PushedAt is the time of the last push.
+}
+
+// ProjectsPage represents the projects index page.
+type ProjectsPage struct {
+	Layout
+	Projects []Project  // The projects to list.
+	Tags     []TagCount // The tag cloud.
+}
+
+// ProjectPage represents a single project detail page.
+type ProjectPage struct {
+	Layout
+	Project          Project    // The project being displayed.
+	Stats            *RepoStats // The repository statistics, if available.
+	CaseStudyExcerpt string     // The excerpt of the case study post, if it is in the database.
+}
+
+// RepoStatsCache caches repository statistics fetched from the GitHub API,
+// keeping request volume well below the unauthenticated rate limit.
+type RepoStatsCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]repoStatsEntry
+}
+
+// repoStatsEntry is a cached RepoStats and the time it was fetched.
+type repoStatsEntry struct {
+	stats     *RepoStats
+	fetchedAt time.Time
+}
+
+// NewRepoStatsCache creates a RepoStatsCache that keeps statistics for ttl.
+func NewRepoStatsCache(ttl time.Duration) *RepoStatsCache {
+	return &RepoStatsCache{
+		ttl:     ttl,
+		entries: make(map[string]repoStatsEntry),
+	}
+}
+
+// Get returns the statistics for the repository at repoUrl, or nil if they are unavailable.
+// Only GitHub repositories are supported. Failures are cached too, so an unreachable API
+// is not queried on every request.
+func (c *RepoStatsCache) Get(repoUrl string) *RepoStats {
+	c.mu.Lock()
+	entry, ok := c.entries[repoUrl]
+	c.mu.Unlock()
+
+	if ok && time.Since(entry.fetchedAt) < c.ttl {
+		return entry.stats
+	}
+
+	stats, err := fetchRepoStats(repoUrl)
+	if err != nil {
+		if ok {
+			return entry.stats
+		}
+		stats = nil
+	}
+
+	c.mu.Lock()
+	c.entries[repoUrl] = repoStatsEntry{stats: stats, fetchedAt: time.Now()}
+	c.mu.Unlock()
+
+	return stats
+}
+
+// FindProject returns the project with the given slug.
+func (db *Database) FindProject(slug string) (Project, bool) {
+	for _, project := range db.Projects {
+		if project.Slug == slug {
+			return project, true
+		}
+	}
+	return Project{}, false
+}
+
+// FindPostByUrl returns the post whose full URL is fullUrl, ignoring a trailing slash.
+func (db *Database) FindPostByUrl(fullUrl string) (Post, bool) {
+	fullUrl = strings.TrimSuffix(fullUrl, "/")
+	for _, post := range db.AllPosts() {
+		if post.FullUrl != "" && strings.TrimSuffix(post.FullUrl, "/") == fullUrl {
+			return post, true
+		}
+	}
+	return Post{}, false
+}
+
+// ProjectsHandler handles the HTTP request for the projects index page.
+func (a *App) ProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	db := a.Data()
+	layout := a.layout(r, a.T(r, "title.projects"))
+	page := ProjectsPage{
+		Layout:   layout,
+		Projects: db.Projects,
+		Tags:     db.TagCloud(layout.Locale),
+	}
+	a.Render(w, r, http.StatusOK, "templates/projects.html", page)
+}
+
+// ProjectHandler handles the HTTP request for a single project page.
+// It renders the project with its repository statistics and case study excerpt,
+// or the 404 page if no project has the requested slug.
+func (a *App) ProjectHandler(w http.ResponseWriter, r *http.Request) {
+	db := a.Data()
+	project, ok := db.FindProject(r.PathValue("slug"))
+	if !ok {
+		a.NotFoundHandler(w, r)
+		return
+	}
+
+	layout := a.layout(r, project.Title)
+	layout.SEO.Description = orDefault(project.Excerpt, layout.SEO.Description)
+	layout.SEO.Type = "article"
+	layout.SEO.ogImage(a, r, a.projectCard(project), ogImagePath("project", "", project.Slug), project.Hero)
+	layout.SEO.StructuredData = []any{a.projectSchema(r, layout, project)}
+
+	page := ProjectPage{
+		Layout:  layout,
+		Project: project,
+	}
+	if project.GitRepo != "" {
+		page.Stats = a.RepoStats.Get(project.GitRepo)
+	}
+	if post, ok := db.FindPostByUrl(project.CaseStudy); ok {
+		page.CaseStudyExcerpt = post.Excerpt
+	}
+
+	a.Render(w, r, http.StatusOK, "templates/project.html", page)
+}
+
+// slugify converts s to a lowercase, hyphen-separated slug, e.g. "Hulu Clone" becomes "hulu-clone".
+func slugify(s string) string {
+	return strings.Trim(slugInvalidChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// ensureProjectSlugs gives every project a unique slug, deriving missing ones from the title.
+// Duplicates get a numeric suffix so each slug resolves to exactly one project.
+func ensureProjectSlugs(projects []Project) {
+	seen := make(map[string]int)
+	for i := range projects {
+		slug := projects[i].Slug
+		if slug == "" {
+			slug = slugify(projects[i].Title)
+		}
+		seen[slug]++
+		if n := seen[slug]; n > 1 {
+			slug = fmt.Sprintf("%s-%d", slug, n)
+		}
+		projects[i].Slug = slug
+	}
+}
+
+// fetchRepoStats fetches the statistics of a GitHub repository from the GitHub API.
+// It returns an error for repositories hosted elsewhere.
+func fetchRepoStats(repoUrl string) (*RepoStats, error) {
+	u, err := url.Parse(repoUrl)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing repository URL: %w", err)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host != "github.com" || len(parts) < 2 {
+		return nil, fmt.Errorf("unsupported repository URL %s", repoUrl)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	req, err := http.NewRequest("GET", "https://api.github.com/repos/"+parts[0]+"/"+strings.TrimSuffix(parts[1], ".git"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; GoClient/1.1)")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received non-200 status code %d", resp.StatusCode)
+	}
+
+	var stats RepoStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("error decoding response body: %w", err)
+	}
+	return &stats, nil
+}
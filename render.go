@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity above which render buffers are not returned to the pool,
+// so one unusually large page does not keep its memory alive.
+const maxPooledBufferSize = 1 << 20
+
+// bufferPool holds the buffers templates are rendered into before being written to the client.
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// Layout holds the data the base layout and shared partials need on every page.
+// Every view model embeds it, so templates can use {{.Title}} or {{.Locale}} on any page.
+type Layout struct {
+	Title       string      // Title is the title of the page.
+	BlogUrl     string      // BlogUrl is the URL of the blog website.
+	ProjectsUrl string      // ProjectsUrl is the URL of the projects website.
+	Locale      string      // Locale is the locale the page is rendered in.
+	Prefix      string      // Prefix is the locale prefix of internal links, e.g. "/zh", or empty.
+	Alternates  []Alternate // Alternates are the page in every supported locale, for hreflang links.
+	Site        SiteConfig  // Site is the identity of the site, translated into Locale.
+	SEO         SEO         // SEO is the page's metadata for search engines and social networks.
+	Nonce       string      // Nonce is the Content-Security-Policy nonce script and style elements must carry.
+}
+
+// Alternate is a page in another locale.
+type Alternate struct {
+	Locale string // Locale is the hreflang value, or "x-default" for the unprefixed page.
+	Name   string // Name is the name of the language in that language.
+	Path   string // Path is the locale-prefixed path of the page, which the language switcher links to.
+	URL    string // URL is the canonical URL of the page in that locale, see canonicalURL.
+}
+
+// Link returns path with the page's locale prefix, e.g. "/about" becomes "/zh/about".
+func (l Layout) Link(path string) string {
+	if path == "/" && l.Prefix != "" {
+		return l.Prefix
+	}
+	return l.Prefix + path
+}
+
+// layout returns the Layout for a page of r with the given title.
+// The site title is appended to the title, or used alone if title is empty.
+func (a *App) layout(r *http.Request, title string) Layout {
+	locale := a.requestLocaleOf(r).Locale
+	if title == "" {
+		title = a.Site.Title
+	} else {
+		title = title + " | " + a.Site.Title
+	}
+
+	l := Layout{
+		Title:       title,
+		BlogUrl:     a.Config.BlogURL,
+		ProjectsUrl: a.Config.ProjectsURL,
+		Locale:      locale,
+		Prefix:      a.localePrefix(r),
+		Site:        a.Site.For(locale),
+		Nonce:       cspNonce(r),
+	}
+	l.SEO = a.seo(r, l.Site, locale)
+
+	for _, locale := range a.Translations.Locales() {
+		l.Alternates = append(l.Alternates, Alternate{
+			Locale: locale,
+			Name:   a.Translations.Translate(locale, "language.name"),
+			Path:   Layout{Prefix: "/" + locale}.Link(r.URL.Path),
+			URL:    a.canonicalURL(r, locale, r.URL.Path),
+		})
+	}
+	l.Alternates = append(l.Alternates, Alternate{
+		Locale: "x-default",
+		Path:   r.URL.Path,
+		URL:    a.canonicalURL(r, a.Translations.Default(), r.URL.Path),
+	})
+
+	return l
+}
+
+// absoluteURL returns the absolute URL of path on this site.
+// It uses SITE_URL when set, otherwise the scheme and host the request was made to,
+// which is only trusted in development as SITE_URL is required in production.
+func (a *App) absoluteURL(r *http.Request, path string) string {
+	base := a.Config.SiteURL
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return strings.TrimSuffix(base, "/") + path
+}
+
+// Render renders the named template with data into a pooled buffer and writes it with the given status.
+// Rendering into a buffer first means a template error never leaves a half-written page
+// with a 200 status: on failure the error is logged and the 500 error page is served instead.
+func (a *App) Render(w http.ResponseWriter, r *http.Request, status int, name string, data any) {
+	buf, err := a.executeTemplate(name, data)
+	if err != nil {
+		a.logger.Printf("Error rendering template %s: %s\n", name, err)
+		a.RenderError(w, r, http.StatusInternalServerError)
+		return
+	}
+	defer releaseBuffer(buf)
+
+	writeHTML(w, status, buf)
+}
+
+// executeTemplate renders the named template with data into a buffer taken from the pool.
+// The caller must release the buffer with releaseBuffer once it has been written.
+func (a *App) executeTemplate(name string, data any) (*bytes.Buffer, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	if err := a.Templates.Execute(buf, name, data); err != nil {
+		releaseBuffer(buf)
+		return nil, err
+	}
+	return buf, nil
+}
+
+// releaseBuffer returns buf to the pool unless it has grown too large.
+func releaseBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// writeHTML writes buf as an HTML response with the given status and Content-Length.
+func writeHTML(w http.ResponseWriter, status int, buf *bytes.Buffer) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
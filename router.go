@@ -0,0 +1,230 @@
+package main
+
+import (
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Route maps a method and path pattern to a handler.
+// A pattern is matched segment by segment: literal segments must match exactly,
+// {name} matches any single non-empty segment and a trailing {name...} matches the rest of the path.
+// Matched wildcards are available to the handler through r.PathValue.
+type Route struct {
+	Method  string
+	Pattern string
+	Handler http.Handler
+	Page    bool // Page marks routes that render an HTML page for visitors, which are listed in the sitemap.
+}
+
+// Router dispatches requests to the first route whose pattern and method match.
+// Unknown paths are served by NotFound. Known paths requested with a method no route
+// accepts are served by MethodNotAllowed with the Allow header set.
+type Router struct {
+	routes           []Route
+	NotFound         http.Handler
+	MethodNotAllowed http.Handler
+}
+
+// NewRouter creates a Router that serves unknown paths with notFound.
+func NewRouter(notFound http.Handler) *Router {
+	return &Router{
+		NotFound: notFound,
+		MethodNotAllowed: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}),
+	}
+}
+
+// Handle registers handler for requests matching method and pattern.
+func (rt *Router) Handle(method, pattern string, handler http.Handler) {
+	rt.routes = append(rt.routes, Route{Method: method, Pattern: pattern, Handler: handler})
+}
+
+// HandleFunc registers handler for requests matching method and pattern.
+func (rt *Router) HandleFunc(method, pattern string, handler http.HandlerFunc) {
+	rt.Handle(method, pattern, handler)
+}
+
+// HandlePage registers handler for GET requests matching pattern, as a route rendering a page.
+func (rt *Router) HandlePage(pattern string, handler http.HandlerFunc) {
+	rt.routes = append(rt.routes, Route{Method: http.MethodGet, Pattern: pattern, Handler: handler, Page: true})
+}
+
+// Routes returns the registered routes in registration order.
+func (rt *Router) Routes() []Route {
+	return append([]Route(nil), rt.routes...)
+}
+
+// ServeHTTP dispatches the request to the matching route.
+// GET routes also answer HEAD requests.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var allowed []string
+
+	for _, route := range rt.routes {
+		params, ok := matchPattern(route.Pattern, r.URL.Path)
+		if !ok {
+			continue
+		}
+
+		if route.Method == r.Method || (route.Method == http.MethodGet && r.Method == http.MethodHead) {
+			for name, value := range params {
+				r.SetPathValue(name, value)
+			}
+			route.Handler.ServeHTTP(w, r)
+			return
+		}
+
+		allowed = append(allowed, route.Method)
+		if route.Method == http.MethodGet {
+			allowed = append(allowed, http.MethodHead)
+		}
+	}
+
+	if len(allowed) == 0 {
+		rt.NotFound.ServeHTTP(w, r)
+		return
+	}
+
+	sort.Strings(allowed)
+	w.Header().Set("Allow", strings.Join(compactStrings(allowed), ", "))
+	rt.MethodNotAllowed.ServeHTTP(w, r)
+}
+
+// matchPattern reports whether path matches pattern and returns the values of its wildcards.
+func matchPattern(pattern, path string) (map[string]string, bool) {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	params := make(map[string]string)
+
+	// A trailing slash is only allowed on the root path.
+	if path != "/" && strings.HasSuffix(path, "/") && !strings.HasSuffix(pattern, "...}") {
+		return nil, false
+	}
+
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "...}") {
+			name := strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "...}")
+			if i >= len(pathSegments) {
+				return nil, false
+			}
+			rest := strings.Join(pathSegments[i:], "/")
+			if rest == "" {
+				return nil, false
+			}
+			params[name] = rest
+			return params, true
+		}
+
+		if i >= len(pathSegments) {
+			return nil, false
+		}
+
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(segment, "{}")] = pathSegments[i]
+			continue
+		}
+
+		if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+
+	if len(patternSegments) != len(pathSegments) {
+		return nil, false
+	}
+	return params, true
+}
+
+// compactStrings removes consecutive duplicates from a sorted slice.
+func compactStrings(values []string) []string {
+	var out []string
+	for i, value := range values {
+		if i == 0 || value != values[i-1] {
+			out = append(out, value)
+		}
+	}
+	return out
+}
+
+// Routes builds the router with every route the application serves.
+// Unknown paths render the 404 page instead of falling through to the home page.
+func (a *App) Routes() (*Router, error) {
+	static, err := fs.Sub(a.Assets, "static")
+	if err != nil {
+		return nil, err
+	}
+
+	router := NewRouter(http.HandlerFunc(a.NotFoundHandler))
+	router.MethodNotAllowed = http.HandlerFunc(a.MethodNotAllowedHandler)
+	router.HandlePage("/", a.HomeHandler)
+	router.HandlePage("/about", a.AboutHandler)
+	router.HandlePage("/blog", a.BlogHandler)
+	router.HandlePage("/blog/{locale}/{slug}", a.PostHandler)
+	router.HandlePage("/projects", a.ProjectsHandler)
+	router.HandlePage("/projects/{slug}", a.ProjectHandler)
+	router.HandlePage("/tags/{tag}", a.TagHandler)
+	router.HandleFunc(http.MethodGet, "/category/{category}", a.CategoryHandler)
+	router.HandleFunc(http.MethodPost, "/contact", a.ContactFormHandler)
+	router.HandleFunc(http.MethodGet, "/sitemap.xml", a.SitemapHandler)
+	router.HandleFunc(http.MethodGet, "/sitemaps/{page}", a.SitemapPageHandler)
+	router.HandleFunc(http.MethodGet, "/robots.txt", a.RobotsHandler)
+	router.HandleFunc(http.MethodGet, "/feed.xml", a.RSSHandler)
+	router.HandleFunc(http.MethodGet, "/atom.xml", a.AtomHandler)
+	router.HandleFunc(http.MethodGet, "/feed.json", a.JSONFeedHandler)
+	router.HandleFunc(http.MethodPost, "/csp-report", a.CSPReportHandler)
+	router.HandleFunc(http.MethodGet, "/img", a.ImageHandler)
+	router.HandleFunc(http.MethodGet, "/og/{kind}/{slug}", a.OGImageHandler)
+	router.HandleFunc(http.MethodGet, "/og/{kind}/{locale}/{slug}", a.OGImageHandler)
+	router.HandleFunc(http.MethodGet, "/static-manifest.json", a.AssetManifestHandler)
+	router.Handle(http.MethodGet, "/static/{path...}", a.staticHandler(static))
+
+	return router, nil
+}
+
+// staticHandler serves files from static, rendering the 404 page for missing files
+// and directories instead of the file server's plain text response and listings.
+// Fingerprinted paths from the asset manifest are cached for a year, as their content never changes.
+// Other paths must be revalidated, using the hash of the file as ETag.
+// If a precompressed sibling such as css/style.css.gz exists and the client accepts gzip, it is served instead,
+// unless it is older than the file, which means it was not regenerated after an edit.
+func (a *App) staticHandler(static fs.FS) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, fingerprinted, hash := a.StaticAssets.Resolve(r.PathValue("path"))
+		info, err := fs.Stat(static, name)
+		if err != nil || info.IsDir() {
+			a.NotFoundHandler(w, r)
+			return
+		}
+
+		if fingerprinted {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		if hash != "" {
+			w.Header().Set("ETag", `"`+hash+`"`)
+		}
+
+		if acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			if gz, err := fs.Stat(static, name+".gz"); err == nil && !gz.IsDir() && !gz.ModTime().Before(info.ModTime()) {
+				if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+					w.Header().Set("Content-Type", contentType)
+				}
+				if hash != "" {
+					w.Header().Set("ETag", `W/"`+hash+`"`)
+				}
+				w.Header().Set("Content-Encoding", "gzip")
+				http.ServeFileFS(w, r, static, name+".gz")
+				return
+			}
+		}
+		http.ServeFileFS(w, r, static, name)
+	})
+}
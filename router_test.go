@@ -0,0 +1,156 @@
+package main
+
+import (
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestApp creates an App serving the embedded templates and static assets,
+// with the built-in projects and no posts. Repository statistics are cached as unavailable,
+// so handlers run without reaching the blog or GitHub APIs.
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+
+	cfg, _, err := LoadConfig([]string{"-env-file", os.DevNull})
+	if err != nil {
+		t.Fatalf("LoadConfig: %s", err)
+	}
+	cfg.AssetsDir = ""
+	cfg.AboutAPI = ""
+
+	app := &App{
+		logger: log.New(io.Discard, "", 0),
+		Config: cfg,
+		Assets: assetsFS(""),
+	}
+	if app.Translations, err = LoadTranslations(app.Assets, "locales", cfg.Locales); err != nil {
+		t.Fatalf("LoadTranslations: %s", err)
+	}
+	if app.Site, err = LoadSiteConfig(app.Assets, "", cfg.Locales); err != nil {
+		t.Fatalf("LoadSiteConfig: %s", err)
+	}
+	if err := app.LoadAboutContent(); err != nil {
+		t.Fatalf("LoadAboutContent: %s", err)
+	}
+
+	static, err := fs.Sub(app.Assets, "static")
+	if err != nil {
+		t.Fatalf("fs.Sub: %s", err)
+	}
+	app.CacheStaticAssets(static)
+	app.Images = NewImageProxy(static, ImageConfig{CacheMaxMB: 1, Concurrency: 1}, t.TempDir())
+	app.CacheTemplates(
+		"templates/index.html",
+		"templates/about.html",
+		"templates/blog.html",
+		"templates/post.html",
+		"templates/projects.html",
+		"templates/project.html",
+		"templates/taxonomy.html",
+		"templates/error.html",
+	)
+
+	projects, _ := fetchProjectsFromAPI()
+	ensureProjectSlugs(projects)
+	app.data.Store(&Database{Projects: projects})
+	app.RepoStats = NewRepoStatsCache(time.Hour)
+	for _, project := range projects {
+		app.RepoStats.entries[project.GitRepo] = repoStatsEntry{fetchedAt: time.Now()}
+	}
+	return app
+}
+
+func TestRouter(t *testing.T) {
+	router := NewRouter(http.NotFoundHandler())
+	for _, pattern := range []string{"/", "/posts", "/posts/{slug}", "/posts/{locale}/{slug}", "/files/{path...}"} {
+		router.HandleFunc(http.MethodGet, pattern, func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, pattern+" slug="+r.PathValue("slug")+" locale="+r.PathValue("locale")+" path="+r.PathValue("path"))
+		})
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/", http.StatusOK, "/ slug= locale= path="},
+		{"/posts", http.StatusOK, "/posts slug= locale= path="},
+		{"/posts/hello", http.StatusOK, "/posts/{slug} slug=hello locale= path="},
+		{"/posts/zh/hello", http.StatusOK, "/posts/{locale}/{slug} slug=hello locale=zh path="},
+		{"/files/a.txt", http.StatusOK, "/files/{path...} slug= locale= path=a.txt"},
+		{"/files/css/site/a.css", http.StatusOK, "/files/{path...} slug= locale= path=css/site/a.css"},
+		{"/files/css/", http.StatusOK, "/files/{path...} slug= locale= path=css"},
+		{"/files/", http.StatusNotFound, ""},
+		{"/files", http.StatusNotFound, ""},
+		{"/posts/", http.StatusNotFound, ""},
+		{"/posts/zh/hello/more", http.StatusNotFound, ""},
+		{"/unknown", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	app := newTestApp(t)
+	router, err := app.Routes()
+	if err != nil {
+		t.Fatalf("Routes: %s", err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		status   int
+		allow    string // allow is the expected Allow header of a 405 response.
+		contains string // contains is a string the body must contain.
+	}{
+		{"page", http.MethodGet, "/about", http.StatusOK, "", "<html"},
+		{"head of a GET route", http.MethodHead, "/about", http.StatusOK, "", ""},
+		{"wrong method on a GET route", http.MethodPost, "/about", http.StatusMethodNotAllowed, "GET, HEAD", ""},
+		{"wrong method on a POST route", http.MethodGet, "/contact", http.StatusMethodNotAllowed, "POST", ""},
+		{"wrong method on a wildcard route", http.MethodDelete, "/static/css/style.css", http.StatusMethodNotAllowed, "GET, HEAD", ""},
+		{"slug capture", http.MethodGet, "/projects/hulu-clone", http.StatusOK, "", "Hulu Clone"},
+		{"unknown slug", http.MethodGet, "/projects/unknown", http.StatusNotFound, "", ""},
+		{"extra segment", http.MethodGet, "/projects/hulu-clone/more", http.StatusNotFound, "", ""},
+		{"rest capture", http.MethodGet, "/static/css/style.css", http.StatusOK, "", "{"},
+		{"rest capture of a missing file", http.MethodGet, "/static/css/missing.css", http.StatusNotFound, "", ""},
+		{"empty rest capture", http.MethodGet, "/static/", http.StatusNotFound, "", ""},
+		{"trailing slash", http.MethodGet, "/about/", http.StatusNotFound, "", ""},
+		{"trailing slash with a capture", http.MethodGet, "/projects/hulu-clone/", http.StatusNotFound, "", ""},
+		{"unknown path", http.MethodGet, "/does-not-exist", http.StatusNotFound, "", ""},
+		{"unknown path with any method", http.MethodPut, "/does-not-exist", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("%s %s: status = %d, want %d", tt.method, tt.path, w.Code, tt.status)
+			}
+			if got := w.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("%s %s: Allow = %q, want %q", tt.method, tt.path, got, tt.allow)
+			}
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("%s %s: body does not contain %q", tt.method, tt.path, tt.contains)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultContentSecurityPolicy is the Content-Security-Policy used unless CSP_POLICY is set.
+// Scripts and style elements must come from this site or carry the request's nonce.
+// Inline style attributes are allowed because templates set background images with them,
+// and images may come from any HTTPS host because project and post images are hosted elsewhere.
+const defaultContentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self' 'nonce-{nonce}'; " +
+	"style-src 'self' 'nonce-{nonce}'; " +
+	"style-src-attr 'unsafe-inline'; " +
+	"img-src 'self' data: https:; " +
+	"font-src 'self' data:; " +
+	"connect-src 'self'; " +
+	"object-src 'none'; " +
+	"base-uri 'self'; " +
+	"form-action 'self'; " +
+	"frame-ancestors 'none'; " +
+	"report-uri /csp-report; " +
+	"report-to csp-endpoint"
+
+// maxCSPReportBytes is the largest violation report body read by CSPReportHandler.
+const maxCSPReportBytes = 64 << 10
+
+// maxCSPReports is the number of violations logged from a single report request.
+const maxCSPReports = 10
+
+// nonceContextKey is the request context key holding the request's CSP nonce.
+type nonceContextKey struct{}
+
+// securityMiddleware sets the security headers configured in Config.Security on every response:
+// Content-Security-Policy, or Content-Security-Policy-Report-Only in report-only mode,
+// Strict-Transport-Security on HTTPS requests, Referrer-Policy, Permissions-Policy,
+// X-Content-Type-Options and X-Frame-Options for browsers that ignore frame-ancestors.
+// Each request gets a fresh nonce, which replaces {nonce} in the policy and is available
+// to templates as {{.Nonce}}.
+func (a *App) securityMiddleware(next http.Handler) http.Handler {
+	cfg := a.Config.Security
+	cspHeader := "Content-Security-Policy"
+	if cfg.CSPReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+	frameOptions := frameOptions(cfg.ContentSecurityPolicy)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce := generateNonce()
+		h := w.Header()
+
+		if cfg.ContentSecurityPolicy != "" {
+			h.Set(cspHeader, strings.ReplaceAll(cfg.ContentSecurityPolicy, "{nonce}", nonce))
+			if strings.Contains(cfg.ContentSecurityPolicy, "report-to csp-endpoint") {
+				h.Set("Reporting-Endpoints", `csp-endpoint="`+a.absoluteURL(r, "/csp-report")+`"`)
+			}
+		}
+		if cfg.HSTSMaxAge > 0 && (r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https") {
+			h.Set("Strict-Transport-Security", "max-age="+strconv.Itoa(cfg.HSTSMaxAge))
+		}
+		if cfg.ReferrerPolicy != "" {
+			h.Set("Referrer-Policy", cfg.ReferrerPolicy)
+		}
+		if cfg.PermissionsPolicy != "" {
+			h.Set("Permissions-Policy", cfg.PermissionsPolicy)
+		}
+		if frameOptions != "" {
+			h.Set("X-Frame-Options", frameOptions)
+		}
+		h.Set("X-Content-Type-Options", "nosniff")
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), nonceContextKey{}, nonce)))
+	})
+}
+
+// generateNonce returns a random base64 nonce for a Content-Security-Policy.
+func generateNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// cspNonce returns the CSP nonce of r, or an empty string if it did not pass through securityMiddleware.
+func cspNonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(nonceContextKey{}).(string)
+	return nonce
+}
+
+// frameOptions returns the X-Frame-Options value matching the frame-ancestors directive of policy,
+// or an empty string if it allows framing by other sites, which X-Frame-Options cannot express.
+func frameOptions(policy string) string {
+	for _, directive := range strings.Split(policy, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), " ")
+		if name != "frame-ancestors" {
+			continue
+		}
+		switch strings.TrimSpace(value) {
+		case "'none'":
+			return "DENY"
+		case "'self'":
+			return "SAMEORIGIN"
+		}
+		return ""
+	}
+	return ""
+}
+
+// cspViolation is a Content-Security-Policy violation reported by a browser.
+type cspViolation struct {
+	DocumentURL string // DocumentURL is the page the violation happened on.
+	BlockedURL  string // BlockedURL is the resource that was blocked, or "inline" or "eval".
+	Directive   string // Directive is the directive that was violated.
+	SourceFile  string // SourceFile is the script or stylesheet that caused the violation, if known.
+	Line        int    // Line is the line in SourceFile, 0 if unknown.
+	Disposition string // Disposition is "enforce" or "report".
+}
+
+// legacyCSPReport is a violation report sent to a report-uri, as application/csp-report.
+type legacyCSPReport struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		BlockedURI         string `json:"blocked-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		Disposition        string `json:"disposition"`
+	} `json:"csp-report"`
+}
+
+// reportingAPIReport is a report sent to a Reporting-Endpoints endpoint, as application/reports+json.
+type reportingAPIReport struct {
+	Type string `json:"type"`
+	Body struct {
+		DocumentURL        string `json:"documentURL"`
+		BlockedURL         string `json:"blockedURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		Disposition        string `json:"disposition"`
+	} `json:"body"`
+}
+
+// parseCSPReports parses a violation report in either the report-uri or the Reporting API format.
+func parseCSPReports(data []byte) ([]cspViolation, error) {
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		var reports []reportingAPIReport
+		if err := json.Unmarshal(data, &reports); err != nil {
+			return nil, err
+		}
+		var violations []cspViolation
+		for _, report := range reports {
+			if report.Type != "csp-violation" {
+				continue
+			}
+			violations = append(violations, cspViolation{
+				DocumentURL: report.Body.DocumentURL,
+				BlockedURL:  report.Body.BlockedURL,
+				Directive:   report.Body.EffectiveDirective,
+				SourceFile:  report.Body.SourceFile,
+				Line:        report.Body.LineNumber,
+				Disposition: report.Body.Disposition,
+			})
+		}
+		return violations, nil
+	}
+
+	var report legacyCSPReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	return []cspViolation{{
+		DocumentURL: report.Report.DocumentURI,
+		BlockedURL:  report.Report.BlockedURI,
+		Directive:   orDefault(report.Report.EffectiveDirective, report.Report.ViolatedDirective),
+		SourceFile:  report.Report.SourceFile,
+		Line:        report.Report.LineNumber,
+		Disposition: report.Report.Disposition,
+	}}, nil
+}
+
+// CSPReportHandler handles the HTTP request for /csp-report, where browsers report
+// Content-Security-Policy violations, and logs each violation.
+// Reports are accepted in the report-uri format (application/csp-report)
+// and the Reporting API format (application/reports+json).
+func (a *App) CSPReportHandler(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/csp-report", "application/reports+json", "application/json":
+	default:
+		a.RenderError(w, r, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCSPReportBytes))
+	if err != nil {
+		status := http.StatusBadRequest
+		if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		a.RenderError(w, r, status)
+		return
+	}
+	violations, err := parseCSPReports(data)
+	if err != nil {
+		a.RenderError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	for _, v := range violations[:min(len(violations), maxCSPReports)] {
+		a.logger.Printf("CSP violation (%s): %q blocked %q on %q at %q line %d\n",
+			orDefault(v.Disposition, "enforce"), v.Directive, v.BlockedURL, v.DocumentURL, v.SourceFile, v.Line)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
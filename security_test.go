@@ -0,0 +1,102 @@
+package main
+
+import (
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var (
+	scriptOrStyleRegex = regexp.MustCompile(`<(script|style)\b[^>]*>`)
+	nonceAttrRegex     = regexp.MustCompile(`\bnonce="([^"]*)"`)
+	cspNonceRegex      = regexp.MustCompile(`'nonce-([^']+)'`)
+)
+
+func TestCSPNonce(t *testing.T) {
+	app := newTestApp(t)
+	router, err := app.Routes()
+	if err != nil {
+		t.Fatalf("Routes: %s", err)
+	}
+	handler := app.securityMiddleware(app.localeMiddleware(router))
+
+	seen := make(map[string]bool)
+	for _, path := range []string{"/about", "/about", "/projects", "/does-not-exist"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		policy := w.Header().Get("Content-Security-Policy")
+		if policy == "" {
+			policy = w.Header().Get("Content-Security-Policy-Report-Only")
+		}
+		matches := cspNonceRegex.FindAllStringSubmatch(policy, -1)
+		if len(matches) == 0 {
+			t.Fatalf("GET %s: policy %q has no nonce", path, policy)
+		}
+		nonce := matches[0][1]
+		for _, m := range matches {
+			if m[1] != nonce {
+				t.Errorf("GET %s: policy has nonces %s and %s", path, nonce, m[1])
+			}
+		}
+		if seen[nonce] {
+			t.Errorf("GET %s: nonce %s was reused", path, nonce)
+		}
+		seen[nonce] = true
+
+		elements := scriptOrStyleRegex.FindAllString(w.Body.String(), -1)
+		if len(elements) == 0 {
+			t.Fatalf("GET %s: page has no script or style elements", path)
+		}
+		for _, element := range elements {
+			attr := nonceAttrRegex.FindStringSubmatch(element)
+			if attr == nil || html.UnescapeString(attr[1]) != nonce {
+				t.Errorf("GET %s: %s does not carry the nonce %s", path, element, nonce)
+			}
+		}
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		security   SecurityConfig
+		forwarded  string
+		header     string
+		want       string
+		wantAbsent []string
+	}{
+		{"enforced policy", SecurityConfig{ContentSecurityPolicy: "default-src 'self'"}, "", "Content-Security-Policy", "default-src 'self'", []string{"Content-Security-Policy-Report-Only"}},
+		{"report-only policy", SecurityConfig{ContentSecurityPolicy: "default-src 'self'", CSPReportOnly: true}, "", "Content-Security-Policy-Report-Only", "default-src 'self'", []string{"Content-Security-Policy"}},
+		{"HSTS over HTTPS", SecurityConfig{HSTSMaxAge: 300}, "https", "Strict-Transport-Security", "max-age=300", nil},
+		{"no HSTS over HTTP", SecurityConfig{HSTSMaxAge: 300}, "", "X-Content-Type-Options", "nosniff", []string{"Strict-Transport-Security"}},
+		{"frame-ancestors none", SecurityConfig{ContentSecurityPolicy: "frame-ancestors 'none'"}, "", "X-Frame-Options", "DENY", nil},
+		{"frame-ancestors self", SecurityConfig{ContentSecurityPolicy: "frame-ancestors 'self'"}, "", "X-Frame-Options", "SAMEORIGIN", nil},
+		{"frame-ancestors other sites", SecurityConfig{ContentSecurityPolicy: "frame-ancestors https://example.com"}, "", "X-Content-Type-Options", "nosniff", []string{"X-Frame-Options"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &App{Config: Config{Security: tt.security}}
+			handler := app.securityMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.forwarded)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if got := w.Header().Get(tt.header); !strings.Contains(got, tt.want) {
+				t.Errorf("%s = %q, want %q", tt.header, got, tt.want)
+			}
+			for _, header := range tt.wantAbsent {
+				if got := w.Header().Get(header); got != "" {
+					t.Errorf("%s = %q, want it unset", header, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SEO holds the metadata search engines and social networks read from a page:
+// the description, canonical URL, OpenGraph and Twitter card tags and schema.org JSON-LD.
+type SEO struct {
+	Description    string // Description is the summary of the page used in search results and previews.
+	Canonical      string // Canonical is the absolute URL search engines should index the page under.
+	Image          string // Image is the absolute URL of the preview image.
+	ImageWidth     int    // ImageWidth is the width of the preview image in pixels, 0 if unknown.
+	ImageHeight    int    // ImageHeight is the height of the preview image in pixels, 0 if unknown.
+	Type           string // Type is the OpenGraph type: website, profile or article.
+	TwitterCard    string // TwitterCard is the Twitter card type: summary or summary_large_image.
+	TwitterSite    string // TwitterSite is the site's Twitter handle, e.g. "@SwayeChateau", or empty.
+	NoIndex        bool   // NoIndex asks search engines not to index the page.
+	StructuredData []any  // StructuredData are the schema.org objects rendered as JSON-LD.
+}
+
+// JSONLD returns the structured data as a JSON-LD document for a script element.
+// A single object is rendered on its own and several as an array.
+// The JSON encoder escapes <, > and &, so the content cannot close the script element.
+func (s SEO) JSONLD() template.JS {
+	var v any = s.StructuredData
+	if len(s.StructuredData) == 1 {
+		v = s.StructuredData[0]
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return template.JS(data)
+}
+
+// schemaPerson is a schema.org Person.
+type schemaPerson struct {
+	Context     string   `json:"@context,omitempty"`
+	Type        string   `json:"@type"`
+	Name        string   `json:"name"`
+	URL         string   `json:"url,omitempty"`
+	Image       string   `json:"image,omitempty"`
+	JobTitle    string   `json:"jobTitle,omitempty"`
+	Description string   `json:"description,omitempty"`
+	KnowsAbout  []string `json:"knowsAbout,omitempty"`
+	SameAs      []string `json:"sameAs,omitempty"`
+}
+
+// schemaCreativeWork is a schema.org CreativeWork describing a project.
+type schemaCreativeWork struct {
+	Context      string       `json:"@context"`
+	Type         string       `json:"@type"`
+	Name         string       `json:"name"`
+	Description  string       `json:"description,omitempty"`
+	URL          string       `json:"url"`
+	Image        string       `json:"image,omitempty"`
+	Keywords     string       `json:"keywords,omitempty"`
+	DateCreated  string       `json:"dateCreated,omitempty"`
+	DateModified string       `json:"dateModified,omitempty"`
+	Author       schemaPerson `json:"author"`
+	SameAs       []string     `json:"sameAs,omitempty"`
+}
+
+// schemaBlogPosting is a schema.org BlogPosting describing a post.
+type schemaBlogPosting struct {
+	Context          string       `json:"@context"`
+	Type             string       `json:"@type"`
+	Headline         string       `json:"headline"`
+	Description      string       `json:"description,omitempty"`
+	URL              string       `json:"url"`
+	MainEntityOfPage string       `json:"mainEntityOfPage"`
+	Image            string       `json:"image,omitempty"`
+	InLanguage       string       `json:"inLanguage,omitempty"`
+	ArticleSection   string       `json:"articleSection,omitempty"`
+	DatePublished    string       `json:"datePublished,omitempty"`
+	DateModified     string       `json:"dateModified,omitempty"`
+	Author           schemaPerson `json:"author"`
+}
+
+// seo returns the default metadata of a page of r: the site tagline as description,
+// the URL of the page in its locale as canonical URL and the site's share image.
+func (a *App) seo(r *http.Request, site SiteConfig, locale string) SEO {
+	return SEO{
+		Description: site.Tagline,
+		Canonical:   a.canonicalURL(r, locale, r.URL.Path),
+		Image:       a.absoluteImage(r, site.Image),
+		Type:        "website",
+		TwitterCard: "summary",
+		TwitterSite: site.TwitterHandle(),
+	}
+}
+
+// canonicalURL returns the absolute URL of the page at path in locale that search engines
+// should index. Pages in the default locale are unprefixed and other locales carry their
+// prefix, so the canonical URL and the hreflang alternates always agree.
+func (a *App) canonicalURL(r *http.Request, locale, path string) string {
+	prefix := ""
+	if locale != a.Translations.Default() {
+		prefix = "/" + locale
+	}
+	return a.absoluteURL(r, Layout{Prefix: prefix}.Link(path))
+}
+
+// absoluteImage returns the absolute URL of an image, which may be a path on this site.
+func (a *App) absoluteImage(r *http.Request, image string) string {
+	if strings.HasPrefix(image, "/") {
+		return a.absoluteURL(r, image)
+	}
+	return image
+}
+
+// ogImage sets the preview image to the generated OpenGraph image of card at path,
+// so every project and post is shared with a consistent card. If the card cannot be drawn
+// the preview image is hero, or the site image if there is no hero image.
+func (s *SEO) ogImage(a *App, r *http.Request, card OGCard, path, hero string) {
+	if !card.Drawable() {
+		if hero != "" {
+			s.Image = a.absoluteImage(r, hero)
+			s.TwitterCard = "summary_large_image"
+		}
+		return
+	}
+	s.Image, s.ImageWidth, s.ImageHeight = a.absoluteURL(r, path), ogImageWidth, ogImageHeight
+	s.TwitterCard = "summary_large_image"
+}
+
+// personSchema returns the site owner as a schema.org Person.
+func (a *App) personSchema(r *http.Request, l Layout) schemaPerson {
+	person := schemaPerson{
+		Context: "https://schema.org",
+		Type:    "Person",
+		Name:    l.Site.Owner,
+		URL:     a.absoluteURL(r, l.Link("/")),
+	}
+	if len(l.Site.Titles) > 0 {
+		person.JobTitle = l.Site.Titles[0]
+	}
+	for _, social := range l.Site.Socials {
+		person.SameAs = append(person.SameAs, social.URL)
+	}
+	return person
+}
+
+// projectSchema returns a project as a schema.org CreativeWork.
+func (a *App) projectSchema(r *http.Request, l Layout, project Project) schemaCreativeWork {
+	author := a.personSchema(r, l)
+	author.Context = ""
+
+	work := schemaCreativeWork{
+		Context:      "https://schema.org",
+		Type:         "CreativeWork",
+		Name:         project.Title,
+		Description:  project.Excerpt,
+		URL:          l.SEO.Canonical,
+		Image:        a.absoluteImage(r, project.Hero),
+		Keywords:     strings.Join(project.Tags, ", "),
+		DateCreated:  formatFeedTime(project.CreatedAt.Time, time.RFC3339),
+		DateModified: formatFeedTime(project.UpdatedAt.Time, time.RFC3339),
+		Author:       author,
+	}
+	for _, link := range []string{project.LiveUrl, project.GitRepo} {
+		if link != "" {
+			work.SameAs = append(work.SameAs, link)
+		}
+	}
+	return work
+}
+
+// postSchema returns a post as a schema.org BlogPosting.
+func (a *App) postSchema(r *http.Request, l Layout, post Post) schemaBlogPosting {
+	author := a.personSchema(r, l)
+	author.Context = ""
+	if post.Author != "" && post.Author != l.Site.Owner {
+		author = schemaPerson{Type: "Person", Name: post.Author}
+	}
+
+	return schemaBlogPosting{
+		Context:          "https://schema.org",
+		Type:             "BlogPosting",
+		Headline:         post.Title,
+		Description:      post.Excerpt,
+		URL:              l.SEO.Canonical,
+		MainEntityOfPage: l.SEO.Canonical,
+		Image:            a.absoluteImage(r, post.HeroImage),
+		InLanguage:       post.Locale,
+		ArticleSection:   post.Category,
+		DatePublished:    formatFeedTime(post.CreatedAt.Time, time.RFC3339),
+		DateModified:     formatFeedTime(latest(post.UpdatedAt.Time, post.CreatedAt.Time), time.RFC3339),
+		Author:           author,
+	}
+}
+
+// TwitterHandle returns the Twitter handle of the site's Twitter profile, e.g. "@SwayeChateau",
+// or an empty string if it has none.
+func (s SiteConfig) TwitterHandle() string {
+	for _, social := range s.Socials {
+		if social.Icon != "twitter" {
+			continue
+		}
+		u, err := url.Parse(social.URL)
+		if err != nil {
+			return ""
+		}
+		if handle, _, _ := strings.Cut(strings.Trim(u.Path, "/"), "/"); handle != "" {
+			return "@" + strings.TrimPrefix(handle, "@")
+		}
+	}
+	return ""
+}
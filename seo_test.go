@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalAndAlternates(t *testing.T) {
+	app := newTestApp(t)
+	app.Config.SiteURL = "https://example.com"
+	router, err := app.Routes()
+	if err != nil {
+		t.Fatalf("Routes: %s", err)
+	}
+	handler := app.localeMiddleware(router)
+
+	alternates := []string{
+		`<link rel="alternate" hreflang="en" href="https://example.com/about">`,
+		`<link rel="alternate" hreflang="zh" href="https://example.com/zh/about">`,
+		`<link rel="alternate" hreflang="x-default" href="https://example.com/about">`,
+	}
+	tests := []struct {
+		path      string
+		canonical string
+	}{
+		{"/about", "https://example.com/about"},
+		{"/en/about", "https://example.com/about"},
+		{"/zh/about", "https://example.com/zh/about"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			body := w.Body.String()
+			if want := `<link rel="canonical" href="` + tt.canonical + `">`; !strings.Contains(body, want) {
+				t.Errorf("body does not contain %s", want)
+			}
+			for _, want := range alternates {
+				if !strings.Contains(body, want) {
+					t.Errorf("body does not contain %s", want)
+				}
+			}
+		})
+	}
+}
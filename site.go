@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// socialIconHover maps the social icons in templates/partials/social-icon.html to their hover colour.
+var socialIconHover = map[string]string{
+	"github":   "hover:text-gray-500",
+	"mastodon": "hover:text-blue-400",
+	"youtube":  "hover:text-red-500",
+	"twitter":  "hover:text-blue-300",
+}
+
+// SiteConfig holds the identity of the site shown on every page.
+// It is loaded from content/site.json, or the file at SITE_CONFIG, and injected into
+// every view model through the Layout.
+type SiteConfig struct {
+	Owner          string              `json:"owner"`           // Owner is the name of the person the site belongs to.
+	Title          string              `json:"title"`           // Title is appended to every page title.
+	ShortName      string              `json:"short_name"`      // ShortName is the short name used in the footer.
+	Tagline        string              `json:"tagline"`         // Tagline is shown under the owner's name on the home page and is the default page description.
+	Image          string              `json:"image"`           // Image is the default preview image for shared links, a /static/ path or URL.
+	Titles         []string            `json:"titles"`          // Titles are the owner's roles, most important first.
+	Summary        string              `json:"summary"`         // Summary is the sentence about the owner's current work shown on the home page.
+	CVURL          string              `json:"cv_url"`          // CVURL is the owner's CV, linked from the home page when set.
+	Avatar         string              `json:"avatar"`          // Avatar is the round picture of the owner shown on small screens, a /static/ path or URL.
+	Portrait       string              `json:"portrait"`        // Portrait is the full picture of the owner shown beside the summary, a /static/ path or URL.
+	HeaderImage    string              `json:"header_image"`    // HeaderImage is the home page header background, a /static/ path or URL.
+	Socials        []SocialLink        `json:"socials"`         // Socials are the owner's social profiles.
+	CopyrightStart int                 `json:"copyright_start"` // CopyrightStart is the first year of the copyright notice.
+	Locales        map[string]SiteText `json:"locales"`         // Locales holds translations of the text fields, keyed by locale.
+}
+
+// SiteText holds the translatable text of the SiteConfig.
+type SiteText struct {
+	Tagline string   `json:"tagline"` // Tagline replaces SiteConfig.Tagline when set.
+	Titles  []string `json:"titles"`  // Titles replaces SiteConfig.Titles when set.
+	Summary string   `json:"summary"` // Summary replaces SiteConfig.Summary when set.
+}
+
+// LoadSiteConfig loads the site configuration from the file at path on disk, or from
+// content/site.json within fsys if path is empty, and validates it for the given locales.
+func LoadSiteConfig(fsys fs.FS, path string, locales []string) (SiteConfig, error) {
+	var site SiteConfig
+	var data []byte
+	var err error
+	if path != "" {
+		data, err = os.ReadFile(path)
+	} else {
+		data, err = fs.ReadFile(fsys, "content/site.json")
+	}
+	if err != nil {
+		return site, fmt.Errorf("error reading site configuration: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &site); err != nil {
+		return site, fmt.Errorf("error decoding site configuration: %w", err)
+	}
+	if err := site.Validate(locales); err != nil {
+		return site, fmt.Errorf("invalid site configuration: %w", err)
+	}
+	return site, nil
+}
+
+// Validate checks the site configuration and returns an error listing every problem found.
+func (s SiteConfig) Validate(locales []string) error {
+	var errs []error
+
+	if s.Owner == "" {
+		errs = append(errs, errors.New("owner is required"))
+	}
+	if s.Title == "" {
+		errs = append(errs, errors.New("title is required"))
+	}
+	images := []struct{ name, value string }{
+		{"image", s.Image},
+		{"avatar", s.Avatar},
+		{"portrait", s.Portrait},
+		{"header_image", s.HeaderImage},
+	}
+	for _, image := range images {
+		if image.value != "" && !strings.HasPrefix(image.value, "/static/") {
+			if err := validateURL(image.value); err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a /static/ path or %w", image.name, err))
+			}
+		}
+	}
+	if s.CVURL != "" {
+		if err := validateURL(s.CVURL); err != nil {
+			errs = append(errs, fmt.Errorf("cv_url %w", err))
+		}
+	}
+	if year := time.Now().Year(); s.CopyrightStart < 1970 || s.CopyrightStart > year {
+		errs = append(errs, fmt.Errorf("copyright_start must be a year between 1970 and %d, got %d", year, s.CopyrightStart))
+	}
+	for _, social := range s.Socials {
+		if social.Name == "" {
+			errs = append(errs, fmt.Errorf("social link %q has no name", social.URL))
+		}
+		if err := validateURL(social.URL); err != nil {
+			errs = append(errs, fmt.Errorf("social link %q %w", social.Name, err))
+		}
+		if _, ok := socialIconHover[social.Icon]; social.Icon != "" && !ok {
+			errs = append(errs, fmt.Errorf("social link %q has unknown icon %q", social.Name, social.Icon))
+		}
+	}
+	for locale := range s.Locales {
+		if !slices.Contains(locales, locale) {
+			errs = append(errs, fmt.Errorf("locales: %s is not in LOCALES", locale))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// For returns the site configuration with its text translated into locale where a translation exists.
+func (s SiteConfig) For(locale string) SiteConfig {
+	text, ok := s.Locales[locale]
+	if !ok {
+		return s
+	}
+	s.Tagline = orDefault(text.Tagline, s.Tagline)
+	s.Summary = orDefault(text.Summary, s.Summary)
+	if len(text.Titles) > 0 {
+		s.Titles = text.Titles
+	}
+	return s
+}
+
+// HoverClass returns the CSS class colouring the link's icon on hover.
+func (l SocialLink) HoverClass() string {
+	return orDefault(socialIconHover[l.Icon], "hover:text-green-400")
+}
+
+// Copyright returns the years of the copyright notice, e.g. "2022–2025", or a single year
+// if the site started this year.
+func (s SiteConfig) Copyright() string {
+	year := time.Now().Year()
+	if s.CopyrightStart == 0 || s.CopyrightStart >= year {
+		return strconv.Itoa(year)
+	}
+	return fmt.Sprintf("%d–%d", s.CopyrightStart, year)
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sitemapXmlns is the namespace of sitemap and sitemap index documents.
+const sitemapXmlns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// SitemapURL is a page listed in the sitemap.
+type SitemapURL struct {
+	Path    string    // Path is the URL path of the page, including its locale prefix.
+	LastMod time.Time // LastMod is when the page content last changed, zero if unknown.
+}
+
+// Sitemap holds the pages listed in /sitemap.xml.
+// It is rebuilt from the routes and the content snapshot whenever the snapshot changes.
+// When there are more than MaxURLs pages, /sitemap.xml is a sitemap index pointing to
+// /sitemaps/1.xml, /sitemaps/2.xml and so on.
+type Sitemap struct {
+	mu       sync.RWMutex
+	routes   []Route      // routes are the routes whose pages are listed.
+	locales  []string     // locales are the supported locales, the first being the default.
+	maxURLs  int          // maxURLs is the number of pages per sitemap file.
+	snapshot string       // snapshot is the hash of the content the sitemap was built from.
+	urls     []SitemapURL // urls are the pages in the sitemap.
+}
+
+// sitemapURLSet is the XML document listing the pages of one sitemap file.
+type sitemapURLSet struct {
+	XMLName xml.Name          `xml:"urlset"`
+	Xmlns   string            `xml:"xmlns,attr"`
+	URLs    []sitemapURLEntry `xml:"url"`
+}
+
+// sitemapURLEntry is a page in a sitemapURLSet.
+type sitemapURLEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemapIndex is the XML document listing the sitemap files.
+type sitemapIndex struct {
+	XMLName  xml.Name          `xml:"sitemapindex"`
+	Xmlns    string            `xml:"xmlns,attr"`
+	Sitemaps []sitemapURLEntry `xml:"sitemap"`
+}
+
+// NewSitemap creates an empty Sitemap for the page routes among routes in every locale,
+// with at most maxURLs pages per sitemap file.
+func NewSitemap(routes []Route, locales []string, maxURLs int) *Sitemap {
+	return &Sitemap{routes: routes, locales: locales, maxURLs: max(maxURLs, 1)}
+}
+
+// Update rebuilds the sitemap from db if its content changed since the last update.
+// It reports whether the sitemap was rebuilt.
+func (s *Sitemap) Update(db *Database) bool {
+	snapshot := db.Snapshot()
+
+	s.mu.RLock()
+	current := s.snapshot
+	s.mu.RUnlock()
+	if snapshot == current {
+		return false
+	}
+
+	urls := s.build(db)
+
+	s.mu.Lock()
+	s.snapshot, s.urls = snapshot, urls
+	s.mu.Unlock()
+	return true
+}
+
+// build returns the pages of the site: every page route without wildcards, and every project,
+// post and tag page, each in every locale it is available in.
+func (s *Sitemap) build(db *Database) []SitemapURL {
+	var urls []SitemapURL
+	add := func(locale, p string, lastMod time.Time) {
+		urls = append(urls, SitemapURL{Path: Layout{Prefix: s.prefix(locale)}.Link(p), LastMod: lastMod})
+	}
+
+	var latestProject, latestPost time.Time
+	for _, project := range db.Projects {
+		latestProject = latest(latestProject, project.UpdatedAt.Time, project.CreatedAt.Time)
+	}
+	posts := db.AllPosts()
+	for _, post := range posts {
+		latestPost = latest(latestPost, post.UpdatedAt.Time, post.CreatedAt.Time)
+	}
+
+	for _, locale := range s.locales {
+		for _, route := range s.routes {
+			// Pages with wildcards are listed below, one for every item of content.
+			if !route.Page || strings.Contains(route.Pattern, "{") {
+				continue
+			}
+			var lastMod time.Time
+			switch route.Pattern {
+			case "/":
+				lastMod = latest(latestProject, latestPost)
+			case "/blog":
+				lastMod = latestPost
+			case "/projects":
+				lastMod = latestProject
+			}
+			add(locale, route.Pattern, lastMod)
+		}
+
+		for _, project := range db.Projects {
+			add(locale, "/projects/"+project.Slug, latest(project.UpdatedAt.Time, project.CreatedAt.Time))
+		}
+
+		for _, tag := range db.TagCloud(locale) {
+			add(locale, taxonomyPath(tag.Key), time.Time{})
+		}
+	}
+
+	// Posts are written in a single locale, so they are listed once under that locale.
+	for _, post := range posts {
+		locale := post.Locale
+		if !s.supported(locale) {
+			locale = s.locales[0]
+		}
+		add(locale, "/blog/"+post.Locale+"/"+post.Slug, latest(post.UpdatedAt.Time, post.CreatedAt.Time))
+	}
+
+	return urls
+}
+
+// prefix returns the path prefix of locale, which is empty for the default locale.
+func (s *Sitemap) prefix(locale string) string {
+	if len(s.locales) == 0 || locale == s.locales[0] {
+		return ""
+	}
+	return "/" + locale
+}
+
+// supported reports whether locale is one of the sitemap's locales.
+func (s *Sitemap) supported(locale string) bool {
+	for _, l := range s.locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Pages returns the number of sitemap files the pages are split into.
+func (s *Sitemap) Pages() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return (len(s.urls) + s.maxURLs - 1) / s.maxURLs
+}
+
+// Page returns the pages in the sitemap file numbered page, starting at 1.
+func (s *Sitemap) Page(page int) ([]SitemapURL, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	start := (page - 1) * s.maxURLs
+	if page < 1 || start >= len(s.urls) {
+		return nil, false
+	}
+	return s.urls[start:min(start+s.maxURLs, len(s.urls))], true
+}
+
+// Snapshot returns a hash of the database content, which changes whenever a project or post does.
+func (db *Database) Snapshot() string {
+	data, err := json.Marshal(db)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// latest returns the latest of times.
+func latest(times ...time.Time) time.Time {
+	var t time.Time
+	for _, candidate := range times {
+		if candidate.After(t) {
+			t = candidate
+		}
+	}
+	return t
+}
+
+// refreshSitemap rebuilds the sitemap if the content changed since it was last built.
+func (a *App) refreshSitemap() {
+	if a.Sitemap != nil && a.Sitemap.Update(a.Data()) {
+		a.logger.Println("Regenerated sitemap")
+	}
+}
+
+// SitemapHandler handles the HTTP request for /sitemap.xml.
+// It lists the pages directly, or the sitemap files when there are too many pages for one.
+func (a *App) SitemapHandler(w http.ResponseWriter, r *http.Request) {
+	pages := a.Sitemap.Pages()
+	if pages <= 1 {
+		urls, _ := a.Sitemap.Page(1)
+		a.writeSitemap(w, r, urls)
+		return
+	}
+
+	index := sitemapIndex{Xmlns: sitemapXmlns}
+	for page := 1; page <= pages; page++ {
+		urls, _ := a.Sitemap.Page(page)
+		var lastMod time.Time
+		for _, u := range urls {
+			lastMod = latest(lastMod, u.LastMod)
+		}
+		index.Sitemaps = append(index.Sitemaps, sitemapURLEntry{
+			Loc:     a.absoluteURL(r, fmt.Sprintf("/sitemaps/%d.xml", page)),
+			LastMod: formatLastMod(lastMod),
+		})
+	}
+	writeXML(w, "application/xml; charset=utf-8", index)
+}
+
+// SitemapPageHandler handles the HTTP request for a sitemap file listed in the sitemap index,
+// e.g. /sitemaps/2.xml.
+func (a *App) SitemapPageHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(strings.TrimSuffix(r.PathValue("page"), ".xml"))
+	if err != nil || !strings.HasSuffix(r.PathValue("page"), ".xml") || a.Sitemap.Pages() <= 1 {
+		a.NotFoundHandler(w, r)
+		return
+	}
+	urls, ok := a.Sitemap.Page(page)
+	if !ok {
+		a.NotFoundHandler(w, r)
+		return
+	}
+	a.writeSitemap(w, r, urls)
+}
+
+// writeSitemap writes a sitemap file listing urls.
+func (a *App) writeSitemap(w http.ResponseWriter, r *http.Request, urls []SitemapURL) {
+	set := sitemapURLSet{Xmlns: sitemapXmlns, URLs: []sitemapURLEntry{}}
+	for _, u := range urls {
+		set.URLs = append(set.URLs, sitemapURLEntry{Loc: a.absoluteURL(r, u.Path), LastMod: formatLastMod(u.LastMod)})
+	}
+	writeXML(w, "application/xml; charset=utf-8", set)
+}
+
+// formatLastMod formats t as a sitemap lastmod date, or an empty string if t is zero.
+func formatLastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// writeXML writes v as an XML document with the given content type.
+func writeXML(w http.ResponseWriter, contentType string, v any) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
+
+// RobotsHandler handles the HTTP request for /robots.txt.
+// Crawlers are allowed everywhere except the ROBOTS_DISALLOW paths when ROBOTS_INDEX is true,
+// which is the default in production, and disallowed everywhere otherwise.
+// The file always points crawlers to the sitemap.
+func (a *App) RobotsHandler(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	if a.Config.Robots.Index {
+		if len(a.Config.Robots.Disallow) == 0 {
+			b.WriteString("Disallow:\n")
+		}
+		for _, p := range a.Config.Robots.Disallow {
+			fmt.Fprintf(&b, "Disallow: %s\n", p)
+		}
+	} else {
+		b.WriteString("Disallow: /\n")
+	}
+	fmt.Fprintf(&b, "\nSitemap: %s\n", a.absoluteURL(r, "/sitemap.xml"))
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// getSitemap requests path from router and decodes the response into v, failing unless it is 200 OK.
+func getSitemap(t *testing.T, router http.Handler, path string, v any) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: status = %d, want %d", path, w.Code, http.StatusOK)
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("GET %s: %s", path, err)
+	}
+}
+
+func TestSitemapSplit(t *testing.T) {
+	app := newTestApp(t)
+	app.Config.SiteURL = "https://example.com"
+	db := *app.Data()
+	db.Posts.Recent = []Post{testPost("en", "hello", 1), testPost("zh", "ni-hao", 2)}
+	app.data.Store(&db)
+
+	router, err := app.Routes()
+	if err != nil {
+		t.Fatalf("Routes: %s", err)
+	}
+
+	// A sitemap large enough for every page is served as a single file.
+	app.Sitemap = NewSitemap(router.Routes(), []string{"en", "zh"}, 50000)
+	app.Sitemap.Update(app.Data())
+	var single sitemapURLSet
+	getSitemap(t, router, "/sitemap.xml", &single)
+	total := len(single.URLs)
+
+	seen := make(map[string]bool)
+	for _, u := range single.URLs {
+		if seen[u.Loc] {
+			t.Errorf("%s is listed twice", u.Loc)
+		}
+		seen[u.Loc] = true
+		if strings.Contains(u.Loc, "{") || strings.Contains(u.Loc, "/img") || strings.Contains(u.Loc, "/static/") {
+			t.Errorf("%s is not a page", u.Loc)
+		}
+	}
+	for _, want := range []string{
+		"https://example.com/",
+		"https://example.com/zh/about",
+		"https://example.com/projects/hulu-clone",
+		"https://example.com/blog/en/hello",
+		"https://example.com/zh/blog/zh/ni-hao",
+	} {
+		if !seen[want] {
+			t.Errorf("%s is not listed", want)
+		}
+	}
+	if seen["https://example.com/zh/blog/en/hello"] {
+		t.Errorf("an English post is listed under the Chinese locale")
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sitemaps/1.xml", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /sitemaps/1.xml of a single sitemap: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	// Above the limit, /sitemap.xml becomes an index of files holding at most maxURLs pages each.
+	const maxURLs = 5
+	app.Sitemap = NewSitemap(router.Routes(), []string{"en", "zh"}, maxURLs)
+	app.Sitemap.Update(app.Data())
+	var index sitemapIndex
+	getSitemap(t, router, "/sitemap.xml", &index)
+	if want := (total + maxURLs - 1) / maxURLs; len(index.Sitemaps) != want {
+		t.Fatalf("index lists %d sitemaps, want %d", len(index.Sitemaps), want)
+	}
+
+	split := make(map[string]bool)
+	for i, entry := range index.Sitemaps {
+		path := fmt.Sprintf("/sitemaps/%d.xml", i+1)
+		if entry.Loc != "https://example.com"+path {
+			t.Errorf("sitemap %d is at %s, want %s", i+1, entry.Loc, "https://example.com"+path)
+		}
+		var set sitemapURLSet
+		getSitemap(t, router, path, &set)
+		if len(set.URLs) == 0 || len(set.URLs) > maxURLs {
+			t.Errorf("%s lists %d pages, want 1 to %d", path, len(set.URLs), maxURLs)
+		}
+		for _, u := range set.URLs {
+			split[u.Loc] = true
+		}
+	}
+	if len(split) != total {
+		t.Errorf("sitemap files list %d distinct pages, want %d", len(split), total)
+	}
+
+	for _, path := range []string{"/sitemaps/0.xml", fmt.Sprintf("/sitemaps/%d.xml", len(index.Sitemaps)+1), "/sitemaps/1", "/sitemaps/one.xml"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status = %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
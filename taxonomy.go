@@ -0,0 +1,180 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TagCount represents a tag or category in the tag cloud.
+type TagCount struct {
+	Key   string // Key is the normalized tag used in URLs.
+	Name  string // Name is the tag as first written in the content.
+	Count int    // Count is the number of projects and posts using the tag.
+	Size  string // Size is the CSS class used to scale the tag in the cloud.
+}
+
+// TaxonomyPage represents a page listing the projects and posts for a tag, which may also be a post category.
+type TaxonomyPage struct {
+	Layout
+	Name     string     // Name is the display name of the tag.
+	Projects []Project  // Projects are the projects with the tag.
+	Posts    []Post     // Posts are the posts in the category.
+	Tags     []TagCount // Tags is the tag cloud.
+}
+
+// tagSymbols are the symbols that tell tags apart, such as "C", "C++" and "C#",
+// spelled out so the key stays a plain URL path segment.
+var tagSymbols = map[rune]string{'+': "plus", '#': "sharp"}
+
+// normalizeTag returns the key used to compare tags and categories,
+// so that "Next.JS", "next-js" and "nextjs" are the same tag.
+// Only letters and digits are kept, except that "+" and "#" are spelled out and a dot
+// starting a word is kept as "dot": "C++" is "cplusplus", "C#" is "csharp" and ".NET" is "dotnet".
+func normalizeTag(tag string) string {
+	var b strings.Builder
+	previous := ' '
+	for _, r := range strings.ToLower(tag) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case tagSymbols[r] != "":
+			b.WriteString(tagSymbols[r])
+		case r == '.' && !unicode.IsLetter(previous) && !unicode.IsDigit(previous):
+			b.WriteString("dot")
+		}
+		previous = r
+	}
+	return b.String()
+}
+
+// WithTag returns the projects tagged with key and the posts in locale whose category is key.
+// Tags and categories are treated as the same vocabulary, so a tag page also lists
+// posts in the matching category and vice versa.
+func (db *Database) WithTag(key, locale string) ([]Project, []Post, string) {
+	var projects []Project
+	var posts []Post
+	name := ""
+
+	for _, project := range db.Projects {
+		for _, tag := range project.Tags {
+			if normalizeTag(tag) == key {
+				projects = append(projects, project)
+				name = orDefault(name, tag)
+				break
+			}
+		}
+	}
+
+	for _, post := range filterPostsByLocale(db.AllPosts(), locale) {
+		if normalizeTag(post.Category) == key {
+			posts = append(posts, post)
+			name = orDefault(name, post.Category)
+		}
+	}
+
+	return projects, posts, name
+}
+
+// TagCloud returns every project tag and the category of every post in locale
+// with the number of projects and posts using it, sorted by count and then name.
+func (db *Database) TagCloud(locale string) []TagCount {
+	counts := make(map[string]*TagCount)
+	add := func(tag string) {
+		key := normalizeTag(tag)
+		if key == "" {
+			return
+		}
+		if _, ok := counts[key]; !ok {
+			counts[key] = &TagCount{Key: key, Name: tag}
+		}
+		counts[key].Count++
+	}
+
+	for _, project := range db.Projects {
+		seen := make(map[string]bool)
+		for _, tag := range project.Tags {
+			if key := normalizeTag(tag); !seen[key] {
+				seen[key] = true
+				add(tag)
+			}
+		}
+	}
+	for _, post := range filterPostsByLocale(db.AllPosts(), locale) {
+		add(post.Category)
+	}
+
+	cloud := make([]TagCount, 0, len(counts))
+	max := 0
+	for _, tag := range counts {
+		cloud = append(cloud, *tag)
+		if tag.Count > max {
+			max = tag.Count
+		}
+	}
+
+	for i := range cloud {
+		switch {
+		case max > 1 && cloud[i].Count == max:
+			cloud[i].Size = "text-2xl"
+		case cloud[i].Count > 1:
+			cloud[i].Size = "text-xl"
+		default:
+			cloud[i].Size = "text-base"
+		}
+	}
+
+	sort.Slice(cloud, func(i, j int) bool {
+		if cloud[i].Count != cloud[j].Count {
+			return cloud[i].Count > cloud[j].Count
+		}
+		return cloud[i].Key < cloud[j].Key
+	})
+	return cloud
+}
+
+// TagHandler handles the HTTP request for the projects with a tag and the posts in the category of the same name.
+// Non-normalized URLs such as /tags/Next.JS redirect permanently to the normalized one,
+// and unknown tags render the 404 page.
+func (a *App) TagHandler(w http.ResponseWriter, r *http.Request) {
+	value := r.PathValue("tag")
+	key := normalizeTag(value)
+	if key != value && key != "" {
+		http.Redirect(w, r, a.localePrefix(r)+taxonomyPath(key), http.StatusMovedPermanently)
+		return
+	}
+
+	locale := a.requestLocaleOf(r).Locale
+	db := a.Data()
+	projects, posts, name := db.WithTag(key, locale)
+	if len(projects) == 0 && len(posts) == 0 {
+		a.NotFoundHandler(w, r)
+		return
+	}
+
+	page := TaxonomyPage{
+		Layout:   a.layout(r, a.T(r, "title.tag", name)),
+		Name:     name,
+		Projects: projects,
+		Posts:    posts,
+		Tags:     db.TagCloud(locale),
+	}
+	a.Render(w, r, http.StatusOK, "templates/taxonomy.html", page)
+}
+
+// CategoryHandler redirects permanently to the tag page of a category, which lists the same posts
+// and projects, so every tag and category has a single canonical URL.
+func (a *App) CategoryHandler(w http.ResponseWriter, r *http.Request) {
+	key := normalizeTag(r.PathValue("category"))
+	if key == "" {
+		a.NotFoundHandler(w, r)
+		return
+	}
+	http.Redirect(w, r, a.localePrefix(r)+taxonomyPath(key), http.StatusMovedPermanently)
+}
+
+// taxonomyPath returns the URL path of the listing page for a tag or category.
+func taxonomyPath(key string) string {
+	return "/tags/" + key
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"Go", "go"},
+		{"Next.JS", "nextjs"},
+		{"next-js", "nextjs"},
+		{"Node.js", "nodejs"},
+		{"Tailwind CSS", "tailwindcss"},
+		{"C", "c"},
+		{"C++", "cplusplus"},
+		{"c++", "cplusplus"},
+		{"C#", "csharp"},
+		{"F#", "fsharp"},
+		{".NET", "dotnet"},
+		{"ASP.NET", "aspnet"},
+		{"Web 2.0", "web20"},
+		{"中文", "中文"},
+		{"---", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeTag(tt.tag); got != tt.want {
+			t.Errorf("normalizeTag(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestTaxonomyRoutes(t *testing.T) {
+	app := newTestApp(t)
+	db := *app.Data()
+	db.Projects = []Project{
+		{Slug: "c", Title: "C Project", Tags: []string{"C"}},
+		{Slug: "cpp", Title: "C++ Project", Tags: []string{"C++"}},
+		{Slug: "csharp", Title: "C# Project", Tags: []string{"C#"}},
+	}
+	post := testPost("en", "hello", 1)
+	post.Title, post.Category = "Hello Post", "C++"
+	db.Posts.Recent = []Post{post}
+	app.data.Store(&db)
+
+	router, err := app.Routes()
+	if err != nil {
+		t.Fatalf("Routes: %s", err)
+	}
+	handler := app.localeMiddleware(router)
+
+	tests := []struct {
+		path        string
+		status      int
+		location    string
+		contains    []string
+		notContains []string
+	}{
+		{"/tags/c", http.StatusOK, "", []string{"C Project", `title="1 item"`}, []string{"C++ Project", "C# Project", "Hello Post"}},
+		{"/tags/cplusplus", http.StatusOK, "", []string{"C++ Project", "Hello Post", `title="2 items"`}, []string{"C Project", "C# Project"}},
+		{"/zh/tags/cplusplus", http.StatusOK, "", []string{"C++ Project", `title="1 项"`}, []string{"Hello Post"}},
+		{"/tags/csharp", http.StatusOK, "", []string{"C# Project"}, []string{"C Project", "C++ Project"}},
+		{"/tags/C++", http.StatusMovedPermanently, "/tags/cplusplus", nil, nil},
+		{"/tags/c%23", http.StatusMovedPermanently, "/tags/csharp", nil, nil},
+		{"/tags/unknown", http.StatusNotFound, "", nil, nil},
+		{"/category/C++", http.StatusMovedPermanently, "/tags/cplusplus", nil, nil},
+		{"/category/cplusplus", http.StatusMovedPermanently, "/tags/cplusplus", nil, nil},
+		{"/zh/category/cplusplus", http.StatusMovedPermanently, "/zh/tags/cplusplus", nil, nil},
+		{"/category/---", http.StatusNotFound, "", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Location"); got != tt.location {
+				t.Errorf("Location = %q, want %q", got, tt.location)
+			}
+			body := html.UnescapeString(w.Body.String())
+			for _, s := range tt.contains {
+				if !strings.Contains(body, s) {
+					t.Errorf("body does not contain %q", s)
+				}
+			}
+			for _, s := range tt.notContains {
+				if strings.Contains(body, s) {
+					t.Errorf("body contains %q", s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TemplateStore holds the parsed page templates.
+// Every page is parsed together with the layouts in dir/layouts and the partials in
+// dir/partials, so a page only has to define its "content" block (and optionally
+// "head" and "scripts") and is rendered through the "base" layout.
+// In production the templates are parsed once at startup. In development the store
+// watches the template directory and re-parses the templates whenever a file changes,
+// keeping the previous good version if parsing fails.
+type TemplateStore struct {
+	logger *log.Logger
+	fsys   fs.FS            // fsys is the file system the templates are read from.
+	dir    string           // dir is the template directory within fsys.
+	files  []string         // files are the page templates to parse.
+	dev    bool             // dev enables reloading and the parse-error overlay.
+	funcs  template.FuncMap // funcs are App-specific functions added to templateFuncs.
+
+	mu        sync.RWMutex
+	templates map[string]*template.Template
+	errs      map[string]error
+	signature string
+}
+
+// NewTemplateStore creates a TemplateStore for the given page templates found in dir within fsys.
+// When dev is true, parse errors are reported in an overlay instead of being fatal.
+// funcs are made available to the templates in addition to templateFuncs.
+func NewTemplateStore(logger *log.Logger, fsys fs.FS, dir string, dev bool, funcs template.FuncMap, files ...string) *TemplateStore {
+	return &TemplateStore{
+		logger:    logger,
+		fsys:      fsys,
+		dir:       dir,
+		files:     files,
+		dev:       dev,
+		funcs:     funcs,
+		templates: make(map[string]*template.Template),
+		errs:      make(map[string]error),
+	}
+}
+
+// Load parses every page template.
+// A template that fails to parse keeps its previous good version, and the error is
+// remembered so it can be shown in the overlay. Load returns the first parse error.
+func (s *TemplateStore) Load() error {
+	var firstErr error
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	shared, err := s.sharedFiles()
+	if err != nil {
+		return fmt.Errorf("error listing layouts and partials: %w", err)
+	}
+
+	for _, filename := range s.files {
+		files := append(append([]string{}, shared...), filename)
+		tmpl, err := template.New(path.Base(filename)).Funcs(templateFuncs).Funcs(s.funcs).ParseFS(s.fsys, files...)
+		if err != nil {
+			s.errs[filename] = err
+			if firstErr == nil {
+				firstErr = fmt.Errorf("error parsing template %s: %w", filename, err)
+			}
+			continue
+		}
+		delete(s.errs, filename)
+		s.templates[filename] = tmpl
+	}
+
+	s.signature = s.dirSignature()
+	return firstErr
+}
+
+// Execute renders the named template with data into w.
+// In development, if the latest version of the template failed to parse,
+// the previous good version is rendered followed by an overlay describing the error.
+// It returns an error if the template has never parsed successfully or fails to render.
+func (s *TemplateStore) Execute(w io.Writer, name string, data any) error {
+	s.mu.RLock()
+	tmpl, ok := s.templates[name]
+	parseErr := s.errs[name]
+	s.mu.RUnlock()
+
+	if !ok && !(s.dev && parseErr != nil) {
+		return fmt.Errorf("template %s not found", name)
+	}
+
+	if ok {
+		if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
+			return err
+		}
+	}
+
+	if s.dev && parseErr != nil {
+		return errorOverlay.Execute(w, struct {
+			Name  string
+			Error string
+			Stale bool
+		}{name, parseErr.Error(), ok})
+	}
+	return nil
+}
+
+// Watch polls the template directory every interval and reloads the templates
+// when a file is added, removed or modified. It returns when stop is closed.
+func (s *TemplateStore) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.mu.RLock()
+			changed := s.dirSignature() != s.signature
+			s.mu.RUnlock()
+			if !changed {
+				continue
+			}
+
+			if err := s.Load(); err != nil {
+				s.logger.Printf("Error reloading templates, keeping previous version: %s\n", err)
+				continue
+			}
+			s.logger.Println("Reloaded templates")
+		}
+	}
+}
+
+// sharedFiles returns the layout and partial templates parsed with every page.
+func (s *TemplateStore) sharedFiles() ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"layouts/*.html", "partials/*.html"} {
+		matches, err := fs.Glob(s.fsys, path.Join(s.dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// dirSignature returns a string that changes whenever a file in the template
+// directory is added, removed or modified.
+func (s *TemplateStore) dirSignature() string {
+	return dirSignature(s.fsys, s.dir)
+}
+
+// dirSignature returns a string that changes whenever a file in dir within fsys
+// is added, removed or modified.
+func dirSignature(fsys fs.FS, dir string) string {
+	var entries []string
+	fs.WalkDir(fsys, dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, fmt.Sprintf("%s:%d:%d", name, info.Size(), info.ModTime().UnixNano()))
+		return nil
+	})
+	sort.Strings(entries)
+	return strings.Join(entries, "|")
+}
+
+// CacheTemplates parses the given page templates into the App's template store.
+// In production, or when the templates are embedded, a parse error is fatal.
+// In development with templates served from disk the server keeps running,
+// the error is shown in an overlay until the template is fixed,
+// and the template directory is watched for changes.
+func (a *App) CacheTemplates(filenames ...string) {
+	dev := !a.Config.IsProduction() && a.Config.AssetsDir != ""
+	funcs := template.FuncMap{
+		"t": func(locale, key string, args ...any) string {
+			return a.Translations.Translate(locale, key, args...)
+		},
+		"timeago": func(locale string, value any) string {
+			return timeAgo(a.Translations, locale, value)
+		},
+		"asset": func(name string) string {
+			return a.StaticAssets.URL(name)
+		},
+		"imgsrc": func(src string, width int) string {
+			return a.Images.URL(src, width)
+		},
+		"srcset": func(src string) string {
+			return a.Images.Srcset(src)
+		},
+	}
+	a.Templates = NewTemplateStore(a.logger, a.Assets, "templates", dev, funcs, filenames...)
+
+	if err := a.Templates.Load(); err != nil {
+		if !dev {
+			a.logger.Fatalf("Error parsing templates: %s\n", err)
+		}
+		a.logger.Printf("Error parsing templates: %s\n", err)
+	}
+
+	if dev {
+		a.logger.Println("Watching templates for changes")
+		go a.Templates.Watch(500*time.Millisecond, nil)
+	}
+}
+
+// templateFuncs are the functions available to every template.
+var templateFuncs = template.FuncMap{
+	"date":      formatDate,
+	"truncate":  truncate,
+	"tag":       normalizeTag,
+	"localdate": localDate,
+	"dict":      dict,
+}
+
+// formatDate formats a time.Time, Timestamp or timestamp string using layout.
+// Strings that cannot be parsed are returned unchanged.
+func formatDate(layout string, value any) string {
+	if t, ok := toTime(value); ok {
+		return t.Format(layout)
+	}
+	if v, ok := value.(string); ok {
+		return v
+	}
+	return ""
+}
+
+// dict builds a map from alternating keys and values, so a partial can be passed
+// more than one value, e.g. {{template "project-card" (dict "Page" $ "Project" .)}}.
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict expects an even number of arguments, got %d", len(pairs))
+	}
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings, got %T", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// truncate shortens s to at most n runes, cutting at the last word boundary
+// and appending an ellipsis when it had to be shortened.
+func truncate(s string, n int) string {
+	runes := []rune(strings.TrimSpace(s))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	cut := string(runes[:n])
+	if i := strings.LastIndexAny(cut, " \t\n"); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+// errorOverlay is rendered in development when a template fails to parse.
+// It is defined inline so that it works even when every template on disk is broken.
+var errorOverlay = template.Must(template.New("overlay").Parse(`
+<div style="position:fixed;inset:0;z-index:9999;overflow:auto;padding:2rem;background:rgba(17,17,17,.92);color:#eee;font-family:monospace">
+    <h1 style="color:#f87171;font-size:1.5rem;margin-bottom:1rem">Template parse error in {{.Name}}</h1>
+    <pre style="white-space:pre-wrap;background:#222;padding:1rem;border-left:4px solid #f87171">{{.Error}}</pre>
+    {{if .Stale}}<p style="margin-top:1rem;color:#9ca3af">The last version that parsed successfully is rendered underneath. Fix the template and reload the page.</p>{{else}}<p style="margin-top:1rem;color:#9ca3af">No version of this template has parsed successfully yet. Fix the template and reload the page.</p>{{end}}
+</div>
+`))
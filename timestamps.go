@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampFormats are the timestamp layouts accepted from the blog and projects APIs.
+// Timestamps without a zone are taken to be UTC.
+var timestampFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// Timestamp is a time decoded from the APIs, which emit RFC 3339 timestamps,
+// SQL datetimes, plain dates and Unix timestamps depending on the endpoint.
+// Empty strings and null decode to the zero time.
+type Timestamp struct {
+	time.Time
+}
+
+// UnmarshalJSON decodes a timestamp string in any of timestampFormats,
+// or a number of seconds since the Unix epoch.
+// Values in any other format are logged and decode to the zero time,
+// so one malformed date does not fail the whole response.
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	if len(data) > 0 && data[0] != '"' {
+		seconds, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			log.Printf("Invalid timestamp %s, leaving it empty\n", data)
+			t.Time = time.Time{}
+			return nil
+		}
+		t.Time = time.Unix(seconds, 0).UTC()
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if strings.TrimSpace(value) == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	parsed, ok := parseTimestamp(value)
+	if !ok {
+		log.Printf("Invalid timestamp %q, leaving it empty\n", value)
+	}
+	t.Time = parsed
+	return nil
+}
+
+// MarshalJSON encodes the timestamp in RFC 3339, or as an empty string if it is zero.
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte(`""`), nil
+	}
+	return json.Marshal(t.Format(time.RFC3339Nano))
+}
+
+// Minutes is a duration in whole minutes decoded from either a JSON number or a numeric string.
+type Minutes int
+
+// UnmarshalJSON decodes a number of minutes such as 5, 5.0, "5" or "".
+// Values that are not numbers, such as "5 min", are logged and decode to 0,
+// so one malformed read time does not fail the whole response.
+func (m *Minutes) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(bytes.TrimSpace(data)), `"`)
+	if value == "" || value == "null" {
+		*m = 0
+		return nil
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid number of minutes %q, leaving it empty\n", value)
+		*m = 0
+		return nil
+	}
+	*m = Minutes(math.Round(n))
+	return nil
+}
+
+// parseTimestamp parses a timestamp in any of timestampFormats.
+func parseTimestamp(value string) (time.Time, bool) {
+	for _, format := range timestampFormats {
+		if t, err := time.Parse(format, strings.TrimSpace(value)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// sortProjectsByDate orders projects by their last update, or creation if they were never updated,
+// newest first. Undated projects keep their order after the dated ones.
+func sortProjectsByDate(projects []Project) {
+	latest := func(p Project) time.Time {
+		if p.UpdatedAt.After(p.CreatedAt.Time) {
+			return p.UpdatedAt.Time
+		}
+		return p.CreatedAt.Time
+	}
+	sort.SliceStable(projects, func(i, j int) bool {
+		return latest(projects[i]).After(latest(projects[j]))
+	})
+}
+
+// timeAgo describes t relative to now in locale, e.g. "3 days ago" or "in 2 hours" in English.
+// The phrases come from the time.* messages of translations.
+func timeAgo(translations *Translations, locale string, value any) string {
+	t, ok := toTime(value)
+	if !ok {
+		return ""
+	}
+
+	d := time.Since(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	var amount int
+	var unit string
+	switch {
+	case d < time.Minute:
+		return translations.Translate(locale, "time.just_now")
+	case d < time.Hour:
+		amount, unit = int(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		amount, unit = int(d/time.Hour), "hour"
+	case d < 30*24*time.Hour:
+		amount, unit = int(d/(24*time.Hour)), "day"
+	case d < 365*24*time.Hour:
+		amount, unit = int(d/(30*24*time.Hour)), "month"
+	default:
+		amount, unit = int(d/(365*24*time.Hour)), "year"
+	}
+	if amount != 1 {
+		unit += "s"
+	}
+
+	duration := translations.Translate(locale, "time."+unit, amount)
+	if future {
+		return translations.Translate(locale, "time.in", duration)
+	}
+	return translations.Translate(locale, "time.ago", duration)
+}
+
+// monthNames are the English month names used by localDate.
+var monthNames = [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+
+// localDate formats the date of t the way it is written in locale,
+// e.g. "January 2, 2006" in English and "2006年1月2日" in Chinese.
+// Unknown locales fall back to English.
+func localDate(locale string, value any) string {
+	t, ok := toTime(value)
+	if !ok {
+		return ""
+	}
+
+	language := strings.ToLower(strings.SplitN(strings.ReplaceAll(locale, "_", "-"), "-", 2)[0])
+	switch language {
+	case "zh", "ja":
+		return fmt.Sprintf("%d年%d月%d日", t.Year(), t.Month(), t.Day())
+	default:
+		return fmt.Sprintf("%s %d, %d", monthNames[t.Month()-1], t.Day(), t.Year())
+	}
+}
+
+// toTime converts a time.Time, Timestamp or timestamp string to a time.Time.
+// It reports false for zero and unparseable values.
+func toTime(value any) (time.Time, bool) {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case Timestamp:
+		t = v.Time
+	case string:
+		t, _ = parseTimestamp(v)
+	}
+	return t, !t.IsZero()
+}
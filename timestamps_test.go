@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDecodePostsWithInvalidReadTime(t *testing.T) {
+	data := `{
+		"recent": [
+			{"slug": "first", "read_time": 5, "created_at": "2024-03-01T10:00:00Z"},
+			{"slug": "second", "read_time": "5 min", "created_at": "2024-03-02"},
+			{"slug": "third", "read_time": "abc"},
+			{"slug": "fourth", "read_time": "7.6"}
+		],
+		"featured": []
+	}`
+
+	var response ApiResponse
+	if err := json.Unmarshal([]byte(data), &response); err != nil {
+		t.Fatalf("Unmarshal: %s", err)
+	}
+
+	want := []struct {
+		slug     string
+		readTime Minutes
+	}{
+		{"first", 5},
+		{"second", 0},
+		{"third", 0},
+		{"fourth", 8},
+	}
+	if len(response.Recent) != len(want) {
+		t.Fatalf("decoded %d posts, want %d", len(response.Recent), len(want))
+	}
+	for i, w := range want {
+		if got := response.Recent[i]; got.Slug != w.slug || got.ReadTime != w.readTime {
+			t.Errorf("post %d = %s with read time %d, want %s with read time %d", i, got.Slug, got.ReadTime, w.slug, w.readTime)
+		}
+	}
+	if response.Recent[1].CreatedAt.IsZero() {
+		t.Errorf("post with an invalid read time lost its creation time")
+	}
+}
+
+func TestTimestampUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		want time.Time
+	}{
+		{`"2024-03-01T10:00:00Z"`, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{`"2024-03-01T12:00:00+02:00"`, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{`"2024-03-01T10:00:00.123456"`, time.Date(2024, 3, 1, 10, 0, 0, 123456000, time.UTC)},
+		{`"2024-03-01 10:00:00"`, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{`"2024-03-01"`, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{`1709287200`, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{`""`, time.Time{}},
+		{`null`, time.Time{}},
+		{`"yesterday"`, time.Time{}},
+		{`1.5`, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			var ts Timestamp
+			if err := json.Unmarshal([]byte(tt.data), &ts); err != nil {
+				t.Fatalf("Unmarshal: %s", err)
+			}
+			if !ts.Equal(tt.want) {
+				t.Errorf("got %s, want %s", ts.Time, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimestampMarshalJSON(t *testing.T) {
+	tests := []struct {
+		ts   Timestamp
+		want string
+	}{
+		{Timestamp{time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)}, `"2024-03-01T10:00:00Z"`},
+		{Timestamp{}, `""`},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.ts)
+		if err != nil {
+			t.Fatalf("Marshal: %s", err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("Marshal(%s) = %s, want %s", tt.ts.Time, data, tt.want)
+		}
+	}
+}
+
+func TestLocalDate(t *testing.T) {
+	date := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		locale string
+		value  any
+		want   string
+	}{
+		{"en", date, "March 1, 2024"},
+		{"zh", date, "2024年3月1日"},
+		{"zh-CN", Timestamp{date}, "2024年3月1日"},
+		{"de", "2024-03-01", "March 1, 2024"},
+		{"en", "not a date", ""},
+		{"en", time.Time{}, ""},
+	}
+	for _, tt := range tests {
+		if got := localDate(tt.locale, tt.value); got != tt.want {
+			t.Errorf("localDate(%q, %v) = %q, want %q", tt.locale, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTimeAgo(t *testing.T) {
+	translations, err := LoadTranslations(assetsFS(""), "locales", []string{"en", "zh"})
+	if err != nil {
+		t.Fatalf("LoadTranslations: %s", err)
+	}
+
+	now := time.Now()
+	tests := []struct {
+		locale string
+		value  any
+		want   string
+	}{
+		{"en", now.Add(-10 * time.Second), "just now"},
+		{"en", now.Add(-time.Minute - time.Second), "1 minute ago"},
+		{"en", now.Add(-3*time.Hour - time.Second), "3 hours ago"},
+		{"en", now.Add(-3*24*time.Hour - time.Second), "3 days ago"},
+		{"en", now.Add(-61 * 24 * time.Hour), "2 months ago"},
+		{"en", now.Add(-800 * 24 * time.Hour), "2 years ago"},
+		{"en", now.Add(2*time.Hour + time.Minute), "in 2 hours"},
+		{"zh", now.Add(-3*24*time.Hour - time.Second), "3 天前"},
+		{"zh", now.Add(2*time.Hour + time.Minute), "2 小时后"},
+		{"en", "", ""},
+	}
+	for _, tt := range tests {
+		if got := timeAgo(translations, tt.locale, tt.value); got != tt.want {
+			t.Errorf("timeAgo(%q, %v) = %q, want %q", tt.locale, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TLS modes accepted in TLS_MODE. An empty mode leaves TLS to a reverse proxy.
+const (
+	TLSModeFiles = "files"
+	TLSModeACME  = "acme"
+)
+
+// acmeCacheDir is the directory the ACME account key and the obtained certificate are cached in,
+// so restarts reuse them instead of ordering a new certificate. Each CA has its own subdirectory;
+// see acmeCachePath.
+const acmeCacheDir = "storage/acme"
+
+// certRenewBefore is how long before it expires a certificate obtained through ACME is renewed.
+const certRenewBefore = 30 * 24 * time.Hour
+
+// CertManager provides the certificate the HTTPS server presents, either read from files
+// or obtained and renewed through ACME.
+type CertManager struct {
+	logger *log.Logger
+	hosts  []string    // hosts are the host names certificates are served and obtained for.
+	acme   *ACMEClient // acme obtains certificates, nil when they are read from files.
+	dir    string      // dir is the directory the ACME account key and certificate are cached in.
+
+	mu         sync.RWMutex
+	cert       *tls.Certificate
+	challenges map[string]string // challenges maps pending http-01 tokens to their key authorizations.
+}
+
+// NewFileCertManager creates a CertManager serving the certificate in certFile and its key in keyFile.
+func NewFileCertManager(logger *log.Logger, hosts []string, certFile, keyFile string) (*CertManager, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading certificate: %w", err)
+	}
+	return &CertManager{logger: logger, hosts: hosts, cert: &cert}, nil
+}
+
+// NewACMECertManager creates a CertManager obtaining certificates for hosts from the ACME CA in cfg.
+// The account key is created on first use and the certificate is reused from the CA's cache
+// directory if it is still valid for every host. Run obtains and renews the certificate.
+func NewACMECertManager(logger *log.Logger, hosts []string, cfg TLSConfig) (*CertManager, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	if cfg.ACMECARoot != "" {
+		data, err := os.ReadFile(cfg.ACMECARoot)
+		if err != nil {
+			return nil, fmt.Errorf("error reading ACME CA root: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.ACMECARoot)
+		}
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}
+	}
+
+	dir := acmeCachePath(cfg.ACMEDirectory)
+	key, err := loadOrCreateKey(filepath.Join(dir, "account.key"))
+	if err != nil {
+		return nil, fmt.Errorf("error loading ACME account key: %w", err)
+	}
+
+	m := &CertManager{
+		logger:     logger,
+		hosts:      hosts,
+		acme:       NewACMEClient(cfg.ACMEDirectory, cfg.ACMEEmail, key, client),
+		dir:        dir,
+		challenges: make(map[string]string),
+	}
+	if cert, err := tls.LoadX509KeyPair(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "cert.key")); err == nil {
+		m.cert = &cert
+	}
+	return m, nil
+}
+
+// acmeCachePath returns the directory the account key and certificate of the ACME CA at
+// directoryURL are cached in: a subdirectory of acmeCacheDir named after a hash of the URL,
+// so after switching CAs, e.g. from Pebble to Let's Encrypt, the other CA's certificate is never served.
+func acmeCachePath(directoryURL string) string {
+	sum := sha256.Sum256([]byte(directoryURL))
+	return filepath.Join(acmeCacheDir, hex.EncodeToString(sum[:8]))
+}
+
+// GetCertificate returns the certificate for a TLS handshake, for tls.Config.GetCertificate.
+// Handshakes for host names outside the allowlist are refused.
+func (m *CertManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if hello.ServerName != "" && len(m.hosts) > 0 && !slices.Contains(m.hosts, strings.ToLower(hello.ServerName)) {
+		return nil, fmt.Errorf("host %q is not allowed", hello.ServerName)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.cert == nil {
+		return nil, errors.New("no certificate has been obtained yet")
+	}
+	return m.cert, nil
+}
+
+// needsRenewal reports whether there is no certificate, or it expires within certRenewBefore
+// or does not cover every host.
+func (m *CertManager) needsRenewal() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.cert == nil || m.cert.Leaf == nil || time.Until(m.cert.Leaf.NotAfter) < certRenewBefore {
+		return true
+	}
+	for _, host := range m.hosts {
+		if m.cert.Leaf.VerifyHostname(host) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Renew obtains a new certificate through ACME if the current one needs renewing,
+// and caches it in the CA's cache directory.
+func (m *CertManager) Renew() error {
+	if m.acme == nil || !m.needsRenewal() {
+		return nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("error generating certificate key: %w", err)
+	}
+	chain, err := m.acme.Obtain(m.hosts, key, func(token, keyAuth string) {
+		m.mu.Lock()
+		m.challenges[token] = keyAuth
+		m.mu.Unlock()
+	})
+	m.mu.Lock()
+	clear(m.challenges)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	cert, err := tls.X509KeyPair(chain, keyPEM)
+	if err != nil {
+		return fmt.Errorf("error parsing certificate: %w", err)
+	}
+
+	if err := saveFile(filepath.Join(m.dir, "cert.key"), keyPEM, 0o600); err != nil {
+		return err
+	}
+	if err := saveFile(filepath.Join(m.dir, "cert.pem"), chain, 0o644); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.cert = &cert
+	m.mu.Unlock()
+	m.logger.Printf("Obtained certificate for %s, valid until %s\n", strings.Join(m.hosts, ", "), cert.Leaf.NotAfter.Format(time.RFC3339))
+	return nil
+}
+
+// Run renews the certificate every twelve hours if needed, retrying after ten minutes when it fails.
+// It never returns.
+func (m *CertManager) Run() {
+	for {
+		wait := 12 * time.Hour
+		if err := m.Renew(); err != nil {
+			m.logger.Printf("Error obtaining certificate: %s\n", err)
+			wait = 10 * time.Minute
+		}
+		time.Sleep(wait)
+	}
+}
+
+// Challenge returns the key authorization of the pending http-01 challenge with token.
+func (m *CertManager) Challenge(token string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keyAuth, ok := m.challenges[token]
+	return keyAuth, ok
+}
+
+// saveFile writes data to name, creating its directory. The file is written under a temporary
+// name and renamed into place, so a crash never leaves a partially written key or certificate.
+func saveFile(name string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", name, time.Now().UnixNano())
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return fmt.Errorf("error writing %s: %w", name, err)
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error saving %s: %w", name, err)
+	}
+	return nil
+}
+
+// requestHost returns the host name of r without its port, in lower case.
+func requestHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// hostMiddleware renders the 421 page for requests whose Host is not in ALLOWED_HOSTS,
+// so the site is not served under host names pointed at it by others. Every host is allowed
+// when ALLOWED_HOSTS is empty.
+func (a *App) hostMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(a.Config.AllowedHosts) > 0 && !slices.Contains(a.Config.AllowedHosts, requestHost(r)) {
+			a.RenderError(w, r, http.StatusMisdirectedRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// redirectHandler handles plain HTTP requests when the server terminates TLS itself.
+// It answers ACME http-01 challenges and permanently redirects every other request for an
+// allowed host to the same URL over HTTPS.
+func (a *App) redirectHandler(certs *CertManager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := strings.CutPrefix(r.URL.Path, "/.well-known/acme-challenge/"); ok {
+			keyAuth, ok := certs.Challenge(token)
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(keyAuth))
+			return
+		}
+
+		host := requestHost(r)
+		if host == "" || (len(a.Config.AllowedHosts) > 0 && !slices.Contains(a.Config.AllowedHosts, host)) {
+			http.Error(w, http.StatusText(http.StatusMisdirectedRequest), http.StatusMisdirectedRequest)
+			return
+		}
+		if a.Config.TLS.Port != "443" {
+			host = net.JoinHostPort(host, a.Config.TLS.Port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// ServeTLS serves handler over HTTPS on TLS_PORT with the certificate from the files or ACME CA
+// configured in TLS_MODE, and serves redirectHandler over HTTP on PORT.
+// It returns when either server fails.
+func (a *App) ServeTLS(handler http.Handler) error {
+	var certs *CertManager
+	var err error
+	switch a.Config.TLS.Mode {
+	case TLSModeFiles:
+		certs, err = NewFileCertManager(a.logger, a.Config.AllowedHosts, a.Config.TLS.CertFile, a.Config.TLS.KeyFile)
+	case TLSModeACME:
+		certs, err = NewACMECertManager(a.logger, a.Config.AllowedHosts, a.Config.TLS)
+	default:
+		err = fmt.Errorf("unknown TLS mode %q", a.Config.TLS.Mode)
+	}
+	if err != nil {
+		return err
+	}
+
+	errs := make(chan error, 2)
+	go func() {
+		a.logger.Println("Starting HTTP redirect server on :" + a.Config.Port)
+		errs <- newServer(":"+a.Config.Port, loggingMiddleware(a.logger, a.redirectHandler(certs))).ListenAndServe()
+	}()
+	go func() {
+		server := newServer(":"+a.Config.TLS.Port, handler)
+		server.TLSConfig = &tls.Config{
+			GetCertificate: certs.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}
+		a.logger.Println("Starting HTTPS server on :" + a.Config.TLS.Port)
+		errs <- server.ListenAndServeTLS("", "")
+	}()
+
+	// The HTTP server answers the challenges, so it is started before the certificate is obtained.
+	if certs.acme != nil {
+		go certs.Run()
+	}
+	return <-errs
+}